@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/config"
+	"github.com/0xsamyy/solwatch/internal/store"
+	"github.com/0xsamyy/solwatch/internal/telegram"
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+const cliUsage = `usage: solwatch [command]
+
+Without a command, runs the bot. Admin commands work on the DB directly
+(no Telegram token or RPC needed) and need the service to be stopped:
+
+  solwatch wallets list
+  solwatch wallets add <address> [account|logs]
+  solwatch wallets remove <address>...
+  solwatch wallets import [-mode merge|replace] [-format json|csv] <file|->
+  solwatch wallets export [-format json|csv] [file|-]
+  solwatch users grant <user-id> viewer|operator|owner
+  solwatch db stats
+  solwatch db backup <file>
+  solwatch db compact
+
+Every command accepts -db <path> (default: DB_PATH or solwatch.db).
+`
+
+// runCLI executes an admin subcommand and returns the process exit code.
+func runCLI(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+	cmd := args[0] + " " + args[1]
+
+	fs := flag.NewFlagSet("solwatch "+cmd, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, cliUsage) }
+	dbPath := fs.String("db", config.DBPath(), "path to the Bolt database")
+	format := fs.String("format", "", "json|csv (import: detected from the content by default)")
+	mode := fs.String("mode", string(store.ImportMerge), "import mode: merge|replace")
+	// Allow flags before, between and after positional arguments.
+	var rest []string
+	for a := args[2:]; ; {
+		if err := fs.Parse(a); err != nil {
+			return 2
+		}
+		if a = fs.Args(); len(a) == 0 {
+			break
+		}
+		rest, a = append(rest, a[0]), a[1:]
+	}
+
+	ctx := context.Background()
+	var err error
+	switch cmd {
+	case "db compact":
+		// Works on the closed file; opening it first would hold the lock.
+		var before, after int64
+		if before, after, err = store.CompactFile(*dbPath); err == nil {
+			fmt.Printf("compacted %s: %d -> %d bytes\n", *dbPath, before, after)
+		}
+	case "wallets list", "wallets add", "wallets remove", "wallets import", "wallets export",
+		"users grant", "db stats", "db backup":
+		err = withStore(*dbPath, func(st *store.Bolt) error {
+			return runStoreCommand(ctx, st, cmd, rest, *format, *mode)
+		})
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "solwatch %s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+func withStore(path string, fn func(st *store.Bolt) error) error {
+	st, err := store.NewBolt(path)
+	if err != nil {
+		return fmt.Errorf("%w (is solwatch still running?)", err)
+	}
+	err = fn(st)
+	if e := st.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+func runStoreCommand(ctx context.Context, st *store.Bolt, cmd string, args []string, format, mode string) error {
+	switch cmd {
+	case "wallets list":
+		recs, err := st.ListWalletRecords(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ADDRESS\tMODE\tLABEL\tTAGS\tADDED")
+		for _, r := range recs {
+			md := r.Mode
+			if md == "" {
+				md = string(tracker.ModeAccount)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Address, md, r.Label, strings.Join(r.Tags, ","), r.AddedAt.Format(time.RFC3339))
+		}
+		return tw.Flush()
+
+	case "wallets add":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("usage: wallets add <address> [account|logs]")
+		}
+		var arg string
+		if len(args) == 2 {
+			arg = args[1]
+		}
+		md, err := tracker.ParseMode(arg)
+		if err != nil {
+			return err
+		}
+		if err := st.AddWallet(ctx, args[0], 0); err != nil {
+			return err
+		}
+		if err := st.SetWalletMode(ctx, args[0], string(md)); err != nil {
+			return err
+		}
+		fmt.Println("added", args[0])
+		return nil
+
+	case "users grant":
+		if len(args) != 2 {
+			return errors.New("usage: users grant <user-id> viewer|operator|owner")
+		}
+		uid, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || uid <= 0 {
+			return fmt.Errorf("invalid user id %q", args[0])
+		}
+		role, err := telegram.ParseRole(args[1])
+		if err != nil {
+			return err
+		}
+		if err := st.SetUserRole(ctx, uid, "", role.String(), 0); err != nil {
+			return err
+		}
+		fmt.Printf("granted %s to %d\n", role, uid)
+		return nil
+
+	case "wallets remove":
+		if len(args) == 0 {
+			return errors.New("usage: wallets remove <address>...")
+		}
+		for _, a := range args {
+			if err := st.RemoveWallet(ctx, a); err != nil {
+				return fmt.Errorf("%s: %w", a, err)
+			}
+			fmt.Println("removed", a)
+		}
+		return nil
+
+	case "wallets import":
+		if len(args) != 1 {
+			return errors.New("usage: wallets import [-mode merge|replace] [-format json|csv] <file|->")
+		}
+		md, err := store.ParseImportMode(mode)
+		if err != nil {
+			return err
+		}
+		data, err := readInput(args[0])
+		if err != nil {
+			return err
+		}
+		f := store.DetectFormat(data)
+		if format != "" {
+			if f, err = store.ParseFormat(format); err != nil {
+				return err
+			}
+		}
+		rep, err := st.ImportWallets(ctx, bytes.NewReader(data), f, md, 0)
+		if err != nil {
+			return err
+		}
+		for _, row := range rep.Rows {
+			if row.Status == store.RowInvalid {
+				fmt.Fprintf(os.Stderr, "line %d %s: %s\n", row.Line, row.Address, row.Err)
+			}
+		}
+		if !rep.Applied {
+			return fmt.Errorf("not applied: %d invalid row(s)", rep.Count(store.RowInvalid))
+		}
+		fmt.Printf("added %d, updated %d, invalid %d, removed %d\n",
+			rep.Count(store.RowAdded), rep.Count(store.RowUpdated), rep.Count(store.RowInvalid), len(rep.Removed))
+		return nil
+
+	case "wallets export":
+		if len(args) > 1 {
+			return errors.New("usage: wallets export [-format json|csv] [file|-]")
+		}
+		f := store.FormatJSON
+		if format != "" {
+			var err error
+			if f, err = store.ParseFormat(format); err != nil {
+				return err
+			}
+		}
+		if len(args) == 0 || args[0] == "-" {
+			return st.ExportWallets(ctx, os.Stdout, f, 0)
+		}
+		out, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		if err := st.ExportWallets(ctx, out, f, 0); err != nil {
+			_ = out.Close()
+			return err
+		}
+		return out.Close()
+
+	case "db stats":
+		s, err := st.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("path:            %s\n", s.Path)
+		fmt.Printf("size:            %d bytes\n", s.Size)
+		fmt.Printf("schema version:  %d (binary %d)\n", s.SchemaVersion, store.SchemaVersion)
+		fmt.Printf("wallets:         %d\n", s.Wallets)
+		fmt.Printf("cursors:         %d\n", s.Cursors)
+		fmt.Printf("history:         %d entries across %d wallets\n", s.HistoryEntries, s.HistoryWallets)
+		fmt.Printf("outbox:          %d pending\n", s.Outbox)
+		return nil
+
+	case "db backup":
+		if len(args) != 1 {
+			return errors.New("usage: db backup <file>")
+		}
+		if err := st.Backup(ctx, args[0]); err != nil {
+			return err
+		}
+		fmt.Println("backed up to", args[0])
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+// readInput reads a file, or stdin for "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
@@ -2,28 +2,45 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	tg "github.com/go-telegram/bot"
 
 	"github.com/0xsamyy/solwatch/internal/config"
 	"github.com/0xsamyy/solwatch/internal/health"
+	"github.com/0xsamyy/solwatch/internal/history"
+	"github.com/0xsamyy/solwatch/internal/logging"
+	"github.com/0xsamyy/solwatch/internal/metrics"
+	"github.com/0xsamyy/solwatch/internal/rpc"
 	"github.com/0xsamyy/solwatch/internal/store"
 	"github.com/0xsamyy/solwatch/internal/telegram"
 	"github.com/0xsamyy/solwatch/internal/tracker"
+	"github.com/0xsamyy/solwatch/internal/watchdog"
 )
 
+// pollTimeout is the Telegram long-polling timeout (the library default).
+const pollTimeout = time.Minute
+
 func main() {
-	// Human-friendly logs
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lmsgprefix)
-	log.SetPrefix("solwatch ")
+	// Offline admin subcommands (wallets ..., db ...) never start the bot
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
 
 	// Load env/config (fatal on error with clear message)
 	cfg := config.MustLoad()
-	log.Println(cfg.RedactedSummary())
+
+	// Leveled logs (LOG_LEVEL, LOG_FORMAT) with secrets redacted; /loglevel
+	// changes the level at runtime
+	if err := logging.Setup(logging.Options{Level: cfg.LogLevel, Format: cfg.LogFormat, Redact: cfg.Redact}); err != nil {
+		fatal("logging", err)
+	}
+	slog.Info("config", "summary", cfg.RedactedSummary())
 
 	// Root context that cancels on SIGINT/SIGTERM
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -32,43 +49,121 @@ func main() {
 	// Open persistent store (Bolt)
 	st, err := store.NewBolt(cfg.DBPath)
 	if err != nil {
-		log.Fatalf("store: %v", err)
+		fatal("store", err)
 	}
 	defer func() {
 		if e := st.Close(); e != nil {
-			log.Printf("store close: %v", e)
+			slog.Error("store close", "error", e)
 		}
 	}()
 
-	// Tracker manager (WS subscriptions for wallets)
-	tm := tracker.NewManager(cfg.HeliusWSS, cfg.Commitment)
+	// Tracker manager (WS subscriptions for wallets, multiplexed over a pool
+	// of connections that fail over between the configured endpoints)
+	eps := make([]tracker.Endpoint, 0, len(cfg.Endpoints))
+	for _, ep := range cfg.Endpoints {
+		eps = append(eps, tracker.Endpoint{URL: ep.URL, Priority: ep.Priority})
+	}
+	tm := tracker.NewManager(eps, cfg.Commitment, cfg.MaxSubsPerConn)
+	tm.UseRPC(rpc.New(cfg.RPCEndpoints...)) // resolves tx signatures behind account updates
+	tm.TrackTokens(cfg.TrackTokens)         // SPL token balance changes
+	tm.UseCursors(st)                       // replay missed activity after reconnects/restarts
+	tm.UseOutbox(st)                        // persist events until Telegram delivered them
+
+	// Activity history: every event is written to the DB, pruned hourly
+	rec := history.New(tm.Bus(), st, cfg.HistoryRetention, cfg.HistoryMaxPerWallet)
+	go rec.Run(ctx)
+
+	// Initialize Telegram bot (long polling is default in this library);
+	// the client remembers the last successful poll for /readyz
+	poll := telegram.NewPollClient(pollTimeout)
+	bot, err := tg.New(cfg.TelegramBotToken, tg.WithHTTPClient(pollTimeout, poll))
+	if err != nil {
+		fatal("telegram init", err)
+	}
 
 	// Health aggregator
 	hlth := health.New(tm, st)
+	hlth.UsePoller(poll.LastPoll)
 
-	// Initialize Telegram bot (long polling is default in this library)
-	bot, err := tg.New(cfg.TelegramBotToken)
-	if err != nil {
-		log.Fatalf("telegram init: %v", err)
+	// Optional HTTP listener: Prometheus /metrics and liveness/readiness probes
+	if cfg.HTTPAddr != "" {
+		hlth.RegisterMetrics(metrics.Default)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Default.Handler())
+		mux.Handle("/healthz", hlth.Liveness())
+		mux.Handle("/readyz", hlth.Readiness(health.ProbeConfig{
+			MaxDroppedRatio: cfg.ReadyMaxDroppedRatio,
+			PollStale:       cfg.ReadyPollStale,
+		}))
+		go serveHTTP(ctx, cfg.HTTPAddr, mux)
 	}
 
+	// Watchdog: tells the admin chat about subscriptions that stay dropped
+	// (and when they are back), through the bus like any other alert
+	wd := watchdog.New(tm, watchdog.Config{
+		Interval:     cfg.WatchdogInterval,
+		DroppedAfter: cfg.WatchdogDroppedAfter,
+		Threshold:    cfg.WatchdogThreshold,
+		Cooldown:     cfg.WatchdogCooldown,
+	})
+	go wd.Run(ctx)
+
 	// Handler wires commands + activity notifications; /kill => cancel()
-	th := telegram.New(bot, tm, st, hlth, cfg.TelegramAdminChatID, cancel)
+	th := telegram.New(bot, tm, st, hlth, cfg.TelegramAdminChatID, cfg.TelegramChatIDs, cfg.TelegramOwnerID, cancel)
+
+	// Wallets nobody watches (older DBs, offline CLI adds) belong to the admin chat
+	if n, err := st.AdoptOrphans(ctx, cfg.TelegramAdminChatID); err != nil {
+		slog.Error("store adopt", "error", err)
+	} else if n > 0 {
+		slog.Info("assigned unowned wallets to the admin chat", "count", n)
+	}
 
-	// On startup: re-subscribe to all persisted wallets
-	if addrs, err := st.ListWallets(ctx); err != nil {
-		log.Printf("store list: %v", err)
+	// On startup: re-subscribe to all persisted wallets, one reference per
+	// watching chat (chats share the upstream subscription)
+	if watches, err := st.ListWatches(ctx); err != nil {
+		slog.Error("store list", "error", err)
 	} else {
-		for _, a := range addrs {
-			if err := tm.Track(ctx, a); err != nil {
-				log.Printf("track %s: %v", a, err)
+		for a, chats := range watches {
+			mode := tracker.ModeAccount
+			if rec, err := st.GetWallet(ctx, a); err == nil {
+				if md, err := tracker.ParseMode(rec.Mode); err == nil {
+					mode = md
+				}
+			}
+			for _, chat := range chats {
+				if err := tm.Track(ctx, chat, a, mode); err != nil {
+					slog.Warn("track failed", "wallet", a, "chat", chat, "error", err)
+				}
 			}
 		}
 	}
 
 	// Block here; returns when context is canceled (/kill or signal)
-	log.Println("started; awaiting Telegram commands")
+	slog.Info("started; awaiting Telegram commands")
 	th.Run(ctx)
+	tm.StopAll()
+
+	slog.Info("shutdown complete")
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
 
-	log.Println("shutdown complete")
+// serveHTTP runs the monitoring listener until ctx is done. It never stops
+// the service: a port clash is logged and monitoring stays off.
+func serveHTTP(ctx context.Context, addr string, h http.Handler) {
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(sctx)
+	}()
+	slog.Info("http listening", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Error("http", "error", err)
+	}
 }
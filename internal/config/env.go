@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,13 +19,57 @@ type Config struct {
 	TelegramAdminChatID int64
 	HeliusWSS           string
 
+	// Optional: more chats (DMs, groups) with their own watchlists. The
+	// admin chat is always allowed.
+	TelegramChatIDs []int64
+
+	// Optional: Telegram user id that is always an owner (needed when the
+	// admin chat is a group).
+	TelegramOwnerID int64
+
+	// Optional: extra WebSocket endpoints for failover (HeliusWSS, if set,
+	// is always included with priority 0).
+	Endpoints []Endpoint
+
+	// Optional: HTTP JSON-RPC endpoints in failover order (default: derived
+	// from Endpoints, by priority)
+	RPCEndpoints []string
+
 	// Optional (with defaults)
-	DBPath     string // default: "solwatch.db"
-	Commitment string // default: "processed" (fastest)
+	DBPath         string // default: "solwatch.db"
+	Commitment     string // default: "processed" (fastest)
+	MaxSubsPerConn int    // default: 100 subscriptions per WebSocket connection
+	TrackTokens    bool   // default: true (watch each wallet's SPL token accounts)
+
+	// Activity history kept in the DB
+	HistoryRetention    time.Duration // default: 720h (30 days); 0 keeps forever
+	HistoryMaxPerWallet int           // default: 1000 entries; 0 = unlimited
+
+	// Optional HTTP listener for /metrics, /healthz and /readyz (e.g.
+	// ":9090"); empty disables it
+	HTTPAddr string
+
+	// Readiness thresholds for /readyz
+	ReadyMaxDroppedRatio float64       // default: 0.2 (20% of tracked wallets)
+	ReadyPollStale       time.Duration // default: 3m without a Telegram poll
+
+	// Watchdog alerts to the admin chat about dropped subscriptions
+	WatchdogInterval     time.Duration // default: 15s between checks; 0 disables the watchdog
+	WatchdogDroppedAfter time.Duration // default: 60s dropped before a wallet is reported; 0 = off
+	WatchdogThreshold    int           // default: 10 dropped wallets at once; 0 = off
+	WatchdogCooldown     time.Duration // default: 5m between two watchdog messages
 
 	// Debug helpers (not strictly required, but nice to have)
 	// LogLevel could be: "debug", "info", "warn", "error" (default: "info")
 	LogLevel string
+	// LogFormat is "text" or "json" (default: "text")
+	LogFormat string
+}
+
+// Endpoint is one WebSocket RPC endpoint; lower Priority is preferred.
+type Endpoint struct {
+	URL      string
+	Priority int
 }
 
 // allowedCommitments is kept small and explicit to avoid surprises.
@@ -61,20 +107,116 @@ func Load() (Config, error) {
 		}
 	}
 
-	// Required: HELIUS_WSS (must start with wss://)
+	// Optional: TELEGRAM_CHAT_IDS (comma-separated; group ids are negative)
+	if v := strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_IDS")); v != "" {
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(item, 10, 64)
+			if err != nil || id == 0 {
+				errs = append(errs, fmt.Sprintf("TELEGRAM_CHAT_IDS: %q is not a valid chat id", item))
+				continue
+			}
+			cfg.TelegramChatIDs = append(cfg.TelegramChatIDs, id)
+		}
+	}
+
+	// Optional: TELEGRAM_OWNER_ID (a user id, so positive)
+	if v := strings.TrimSpace(os.Getenv("TELEGRAM_OWNER_ID")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			errs = append(errs, fmt.Sprintf("TELEGRAM_OWNER_ID must be a Telegram user id, got %q", v))
+		} else {
+			cfg.TelegramOwnerID = id
+		}
+	}
+
+	// Required: HELIUS_WSS (must start with wss://), unless WSS_ENDPOINTS is set
 	cfg.HeliusWSS = strings.TrimSpace(os.Getenv("HELIUS_WSS"))
-	if cfg.HeliusWSS == "" {
-		errs = append(errs, "HELIUS_WSS is required (your Helius WebSocket RPC URL, incl. api key)")
-	} else if !strings.HasPrefix(strings.ToLower(cfg.HeliusWSS), "wss://") {
-		errs = append(errs, fmt.Sprintf("HELIUS_WSS must start with wss://, got %q", cfg.HeliusWSS))
+	if cfg.HeliusWSS != "" {
+		if !strings.HasPrefix(strings.ToLower(cfg.HeliusWSS), "wss://") {
+			errs = append(errs, fmt.Sprintf("HELIUS_WSS must start with wss://, got %q", RedactURL(cfg.HeliusWSS)))
+		} else {
+			cfg.Endpoints = append(cfg.Endpoints, Endpoint{URL: cfg.HeliusWSS, Priority: 0})
+		}
 	}
 
-	// Optional: DB_PATH (default: solwatch.db)
-	cfg.DBPath = strings.TrimSpace(os.Getenv("DB_PATH"))
-	if cfg.DBPath == "" {
-		cfg.DBPath = "solwatch.db"
+	// Optional: WSS_ENDPOINTS (comma-separated "url|priority"; priority defaults to list position)
+	if v := strings.TrimSpace(os.Getenv("WSS_ENDPOINTS")); v != "" {
+		for i, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			ep := Endpoint{URL: item, Priority: i + 1}
+			if j := strings.LastIndex(item, "|"); j >= 0 {
+				p, err := strconv.Atoi(strings.TrimSpace(item[j+1:]))
+				if err != nil || p < 0 {
+					errs = append(errs, fmt.Sprintf("WSS_ENDPOINTS: bad priority in %q (want url|N)", RedactURL(item)))
+					continue
+				}
+				ep.URL, ep.Priority = strings.TrimSpace(item[:j]), p
+			}
+			if !strings.HasPrefix(strings.ToLower(ep.URL), "wss://") {
+				errs = append(errs, fmt.Sprintf("WSS_ENDPOINTS: %q must start with wss://", RedactURL(ep.URL)))
+				continue
+			}
+			if ep.URL == cfg.HeliusWSS {
+				continue // already included with priority 0
+			}
+			cfg.Endpoints = append(cfg.Endpoints, ep)
+		}
+	}
+	if cfg.HeliusWSS == "" && strings.TrimSpace(os.Getenv("WSS_ENDPOINTS")) == "" {
+		errs = append(errs, "HELIUS_WSS is required (your Helius WebSocket RPC URL, incl. api key), or set WSS_ENDPOINTS")
+	}
+	if cfg.HeliusWSS == "" && len(cfg.Endpoints) > 0 {
+		// Treat the preferred endpoint as primary.
+		primary := cfg.Endpoints[0]
+		for _, ep := range cfg.Endpoints[1:] {
+			if ep.Priority < primary.Priority {
+				primary = ep
+			}
+		}
+		cfg.HeliusWSS = primary.URL
+	}
+
+	// Optional: HELIUS_RPC, then RPC_ENDPOINTS (comma-separated), tried in
+	// that order (default: every WebSocket endpoint by priority, with
+	// wss:// -> https://)
+	addRPC := func(name, u string) {
+		if l := strings.ToLower(u); !strings.HasPrefix(l, "https://") && !strings.HasPrefix(l, "http://") {
+			errs = append(errs, fmt.Sprintf("%s: %q must start with https:// or http://", name, RedactURL(u)))
+			return
+		}
+		for _, have := range cfg.RPCEndpoints {
+			if have == u {
+				return
+			}
+		}
+		cfg.RPCEndpoints = append(cfg.RPCEndpoints, u)
+	}
+	if v := strings.TrimSpace(os.Getenv("HELIUS_RPC")); v != "" {
+		addRPC("HELIUS_RPC", v)
+	}
+	for _, item := range strings.Split(os.Getenv("RPC_ENDPOINTS"), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			addRPC("RPC_ENDPOINTS", item)
+		}
+	}
+	if os.Getenv("HELIUS_RPC") == "" && os.Getenv("RPC_ENDPOINTS") == "" {
+		eps := append([]Endpoint(nil), cfg.Endpoints...)
+		sort.SliceStable(eps, func(i, j int) bool { return eps[i].Priority < eps[j].Priority })
+		for _, ep := range eps {
+			addRPC("WSS_ENDPOINTS", "https://"+ep.URL[len("wss://"):])
+		}
 	}
 
+	// Optional: DB_PATH (default: solwatch.db)
+	cfg.DBPath = dbPath()
+
 	// Optional: COMMITMENT (default: processed; normalize to lowercase)
 	commitment := strings.TrimSpace(os.Getenv("COMMITMENT"))
 	if commitment == "" {
@@ -87,6 +229,110 @@ func Load() (Config, error) {
 		cfg.Commitment = commitment
 	}
 
+	// Optional: MAX_SUBS_PER_CONN (default: 100)
+	cfg.MaxSubsPerConn = 100
+	if v := strings.TrimSpace(os.Getenv("MAX_SUBS_PER_CONN")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 10000 {
+			errs = append(errs, fmt.Sprintf("MAX_SUBS_PER_CONN must be an integer in 1..10000, got %q", v))
+		} else {
+			cfg.MaxSubsPerConn = n
+		}
+	}
+
+	// Optional: TRACK_TOKEN_ACCOUNTS (default: true)
+	cfg.TrackTokens = true
+	if v := strings.TrimSpace(os.Getenv("TRACK_TOKEN_ACCOUNTS")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("TRACK_TOKEN_ACCOUNTS must be true|false, got %q", v))
+		} else {
+			cfg.TrackTokens = b
+		}
+	}
+
+	// Optional: HISTORY_RETENTION (default: 720h; Go duration, 0 disables)
+	cfg.HistoryRetention = 720 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("HISTORY_RETENTION")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			errs = append(errs, fmt.Sprintf("HISTORY_RETENTION must be a duration like 720h (0 = forever), got %q", v))
+		} else {
+			cfg.HistoryRetention = d
+		}
+	}
+
+	// Optional: HISTORY_MAX_PER_WALLET (default: 1000; 0 = unlimited)
+	cfg.HistoryMaxPerWallet = 1000
+	if v := strings.TrimSpace(os.Getenv("HISTORY_MAX_PER_WALLET")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, fmt.Sprintf("HISTORY_MAX_PER_WALLET must be a non-negative integer, got %q", v))
+		} else {
+			cfg.HistoryMaxPerWallet = n
+		}
+	}
+
+	// Optional: HTTP_ADDR (default: disabled)
+	cfg.HTTPAddr = strings.TrimSpace(os.Getenv("HTTP_ADDR"))
+	if cfg.HTTPAddr != "" && !strings.Contains(cfg.HTTPAddr, ":") {
+		errs = append(errs, fmt.Sprintf("HTTP_ADDR must be host:port or :port, got %q", cfg.HTTPAddr))
+	}
+
+	// Optional: READY_MAX_DROPPED_RATIO (default: 0.2; 0 disables the check)
+	cfg.ReadyMaxDroppedRatio = 0.2
+	if v := strings.TrimSpace(os.Getenv("READY_MAX_DROPPED_RATIO")); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			errs = append(errs, fmt.Sprintf("READY_MAX_DROPPED_RATIO must be a number in 0..1, got %q", v))
+		} else {
+			cfg.ReadyMaxDroppedRatio = f
+		}
+	}
+
+	// Optional: READY_POLL_STALE (default: 3m; 0 disables the check)
+	cfg.ReadyPollStale = 3 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("READY_POLL_STALE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			errs = append(errs, fmt.Sprintf("READY_POLL_STALE must be a duration like 3m (0 = off), got %q", v))
+		} else {
+			cfg.ReadyPollStale = d
+		}
+	}
+
+	// Optional: WATCHDOG_INTERVAL, WATCHDOG_DROPPED_AFTER, WATCHDOG_COOLDOWN
+	// (durations; 0 disables) and WATCHDOG_DROPPED_THRESHOLD (count; 0 disables)
+	cfg.WatchdogInterval = 15 * time.Second
+	cfg.WatchdogDroppedAfter = time.Minute
+	cfg.WatchdogCooldown = 5 * time.Minute
+	for _, d := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"WATCHDOG_INTERVAL", &cfg.WatchdogInterval},
+		{"WATCHDOG_DROPPED_AFTER", &cfg.WatchdogDroppedAfter},
+		{"WATCHDOG_COOLDOWN", &cfg.WatchdogCooldown},
+	} {
+		if v := strings.TrimSpace(os.Getenv(d.env)); v != "" {
+			dur, err := time.ParseDuration(v)
+			if err != nil || dur < 0 {
+				errs = append(errs, fmt.Sprintf("%s must be a duration like 60s (0 = off), got %q", d.env, v))
+			} else {
+				*d.dst = dur
+			}
+		}
+	}
+	cfg.WatchdogThreshold = 10
+	if v := strings.TrimSpace(os.Getenv("WATCHDOG_DROPPED_THRESHOLD")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, fmt.Sprintf("WATCHDOG_DROPPED_THRESHOLD must be a non-negative integer, got %q", v))
+		} else {
+			cfg.WatchdogThreshold = n
+		}
+	}
+
 	// Optional: LOG_LEVEL (default: info)
 	logLevel := strings.TrimSpace(strings.ToLower(os.Getenv("LOG_LEVEL")))
 	switch logLevel {
@@ -100,6 +346,16 @@ func Load() (Config, error) {
 	}
 	cfg.LogLevel = logLevel
 
+	// Optional: LOG_FORMAT (default: text)
+	cfg.LogFormat = strings.TrimSpace(strings.ToLower(os.Getenv("LOG_FORMAT")))
+	switch cfg.LogFormat {
+	case "":
+		cfg.LogFormat = "text"
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Sprintf("LOG_FORMAT must be text|json, got %q", cfg.LogFormat))
+	}
+
 	if len(errs) > 0 {
 		return Config{}, errors.New("config validation error:\n  - " + strings.Join(errs, "\n  - "))
 	}
@@ -107,6 +363,21 @@ func Load() (Config, error) {
 	return cfg, nil
 }
 
+// DBPath returns DB_PATH (from the environment or .env, default
+// solwatch.db) without requiring the rest of the config. Used by the
+// offline admin subcommands.
+func DBPath() string {
+	_ = godotenv.Load()
+	return dbPath()
+}
+
+func dbPath() string {
+	if p := strings.TrimSpace(os.Getenv("DB_PATH")); p != "" {
+		return p
+	}
+	return "solwatch.db"
+}
+
 // MustLoad is a convenience for main(): exit fast with a readable error.
 func MustLoad() Config {
 	cfg, err := Load()
@@ -122,16 +393,48 @@ func MustLoad() Config {
 // Useful to log at startup for quick debugging without leaking secrets.
 func (c Config) RedactedSummary() string {
 	return fmt.Sprintf(
-		"config{ commitment=%s, db=%s, helius_wss=%s, telegram_bot_token=%s, admin_chat_id=%d, log_level=%s }",
+		"config{ commitment=%s, db=%s, helius_wss=%s, endpoints=%s, rpc_endpoints=%s, max_subs_per_conn=%d, track_tokens=%t, history_retention=%s, history_max_per_wallet=%d, http_addr=%s, ready_max_dropped_ratio=%g, ready_poll_stale=%s, watchdog_interval=%s, watchdog_dropped_after=%s, watchdog_threshold=%d, watchdog_cooldown=%s, telegram_bot_token=%s, admin_chat_id=%d, chat_ids=%v, owner_id=%d, log_level=%s, log_format=%s }",
 		c.Commitment,
 		c.DBPath,
-		redactURL(c.HeliusWSS),
+		RedactURL(c.HeliusWSS),
+		c.endpointsSummary(),
+		c.rpcSummary(),
+		c.MaxSubsPerConn,
+		c.TrackTokens,
+		c.HistoryRetention,
+		c.HistoryMaxPerWallet,
+		c.HTTPAddr,
+		c.ReadyMaxDroppedRatio,
+		c.ReadyPollStale,
+		c.WatchdogInterval,
+		c.WatchdogDroppedAfter,
+		c.WatchdogThreshold,
+		c.WatchdogCooldown,
 		redactToken(c.TelegramBotToken),
 		c.TelegramAdminChatID,
+		c.TelegramChatIDs,
+		c.TelegramOwnerID,
 		c.LogLevel,
+		c.LogFormat,
 	)
 }
 
+func (c Config) endpointsSummary() string {
+	parts := make([]string, 0, len(c.Endpoints))
+	for _, ep := range c.Endpoints {
+		parts = append(parts, fmt.Sprintf("%s|%d", RedactURL(ep.URL), ep.Priority))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func (c Config) rpcSummary() string {
+	parts := make([]string, 0, len(c.RPCEndpoints))
+	for _, u := range c.RPCEndpoints {
+		parts = append(parts, RedactURL(u))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
 func redactToken(tok string) string {
 	// Keep only first 6 chars if long, else "***"
 	if len(tok) > 6 {
@@ -143,7 +446,18 @@ func redactToken(tok string) string {
 	return "***"
 }
 
-func redactURL(u string) string {
+// Redact hides the secrets of c in s: API keys in URLs and the bot token
+// (which go-telegram puts in request URLs, and so in HTTP errors).
+func (c Config) Redact(s string) string {
+	s = RedactURL(s)
+	if c.TelegramBotToken != "" {
+		s = strings.ReplaceAll(s, c.TelegramBotToken, redactToken(c.TelegramBotToken))
+	}
+	return s
+}
+
+// RedactURL hides the value of an api-key query parameter in u.
+func RedactURL(u string) string {
 	// If the URL contains an API key as query, hide it crudely.
 	// e.g., wss://.../?api-key=abcdef -> wss://.../?api-key=*** (redacted)
 	parts := strings.Split(u, "api-key=")
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setEnv sets the required variables plus the given ones; every endpoint
+// variable not given is cleared.
+func setEnv(t *testing.T, kv map[string]string) {
+	t.Helper()
+	base := map[string]string{
+		"TELEGRAM_BOT_TOKEN":     "123456:token",
+		"TELEGRAM_ADMIN_CHAT_ID": "42",
+		"TELEGRAM_OWNER_ID":      "",
+		"HELIUS_WSS":             "",
+		"WSS_ENDPOINTS":          "",
+		"HELIUS_RPC":             "",
+		"RPC_ENDPOINTS":          "",
+	}
+	for k, v := range kv {
+		base[k] = v
+	}
+	for k, v := range base {
+		t.Setenv(k, v)
+	}
+}
+
+func TestLoadEndpoints(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		wss  []Endpoint
+		rpc  []string
+	}{
+		{
+			name: "primary only",
+			env:  map[string]string{"HELIUS_WSS": "wss://a.example/?api-key=K"},
+			wss:  []Endpoint{{"wss://a.example/?api-key=K", 0}},
+			rpc:  []string{"https://a.example/?api-key=K"},
+		},
+		{
+			name: "list with explicit and positional priorities",
+			env: map[string]string{
+				"HELIUS_WSS":    "wss://a.example",
+				"WSS_ENDPOINTS": "wss://c.example|5, wss://b.example ,wss://a.example|9",
+			},
+			wss: []Endpoint{{"wss://a.example", 0}, {"wss://c.example", 5}, {"wss://b.example", 2}},
+			rpc: []string{"https://a.example", "https://b.example", "https://c.example"},
+		},
+		{
+			name: "list without primary",
+			env:  map[string]string{"WSS_ENDPOINTS": "wss://b.example|2,wss://c.example|1"},
+			wss:  []Endpoint{{"wss://b.example", 2}, {"wss://c.example", 1}},
+			rpc:  []string{"https://c.example", "https://b.example"},
+		},
+		{
+			name: "explicit rpc replaces derived",
+			env: map[string]string{
+				"HELIUS_WSS":    "wss://a.example",
+				"HELIUS_RPC":    "https://rpc.example",
+				"RPC_ENDPOINTS": "http://backup.example, https://rpc.example",
+			},
+			wss: []Endpoint{{"wss://a.example", 0}},
+			rpc: []string{"https://rpc.example", "http://backup.example"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Endpoints, tt.wss) {
+				t.Errorf("Endpoints = %v, want %v", cfg.Endpoints, tt.wss)
+			}
+			if !reflect.DeepEqual(cfg.RPCEndpoints, tt.rpc) {
+				t.Errorf("RPCEndpoints = %v, want %v", cfg.RPCEndpoints, tt.rpc)
+			}
+		})
+	}
+}
+
+func TestLoadEndpointErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"no endpoint", nil, "HELIUS_WSS is required"},
+		{"bad scheme", map[string]string{"HELIUS_WSS": "https://a.example"}, "HELIUS_WSS must start with wss://"},
+		{"bad priority", map[string]string{"WSS_ENDPOINTS": "wss://a.example|x"}, "bad priority"},
+		{"negative priority", map[string]string{"WSS_ENDPOINTS": "wss://a.example|-1"}, "bad priority"},
+		{"list bad scheme", map[string]string{"WSS_ENDPOINTS": "ws://a.example"}, "must start with wss://"},
+		{"rpc bad scheme", map[string]string{"HELIUS_WSS": "wss://a.example", "RPC_ENDPOINTS": "wss://b.example"}, "RPC_ENDPOINTS"},
+		{"bad owner", map[string]string{"HELIUS_WSS": "wss://a.example", "TELEGRAM_OWNER_ID": "-5"}, "TELEGRAM_OWNER_ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
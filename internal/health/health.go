@@ -2,8 +2,11 @@ package health
 
 import (
 	"context"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/0xsamyy/solwatch/internal/metrics"
 	"github.com/0xsamyy/solwatch/internal/tracker"
 )
 
@@ -13,11 +16,14 @@ type WalletLister interface {
 }
 
 // Health exposes a read-only snapshot of service state for the /health command.
+// Service-wide counters come from the metrics package.
 type Health struct {
-	tm  *tracker.Manager
-	st  WalletLister
+	tm       *tracker.Manager
+	st       WalletLister
+	lastPoll func() time.Time // optional; see UsePoller
 
-	// Future: counters/metrics (e.g., reconnects, errors) can be injected here.
+	mu            sync.Mutex
+	mismatchSince time.Time // see notReady
 }
 
 // New returns a Health aggregator bound to the tracker manager and store.
@@ -25,24 +31,53 @@ func New(tm *tracker.Manager, st WalletLister) *Health {
 	return &Health{tm: tm, st: st}
 }
 
+// UsePoller reports the time of the last successful Telegram poll in
+// snapshots (readiness fails when it goes stale). Call it before serving.
+func (h *Health) UsePoller(lastPoll func() time.Time) {
+	h.lastPoll = lastPoll
+}
+
 // Report is the struct returned to the caller (Telegram handler) for formatting.
 type Report struct {
 	GeneratedAt time.Time `json:"generated_at"`
 
 	// From tracker.Manager.Stats()
 	Tracked int      `json:"tracked_in_memory"`
-	Open    int      `json:"open_subscriptions"`
+	Open    int      `json:"open_subscriptions"` // subscribe ACK received
 	Dropped []string `json:"dropped_subscriptions"`
 
+	// Per-wallet counters, and the wallets that reconnected most
+	Subscribers []tracker.SubscriberStats `json:"subscribers"`
+	Flapping    []tracker.SubscriberStats `json:"flapping"` // top maxFlapping by disconnects
+
+	// From tracker.Manager.Connected() / Failures()
+	Connected int               `json:"connected_subscriptions"` // socket up, ACK or not
+	Failed    []tracker.Failure `json:"failed_subscriptions"`
+
+	// From tracker.Manager.Conns() / Endpoints()
+	Conns       int                    `json:"connections"`
+	Connections []tracker.ConnInfo     `json:"connection_details"`
+	Endpoints   []tracker.EndpointInfo `json:"endpoints"`
+
 	// From persistent store
 	TrackedPersisted int `json:"tracked_in_store"`
 
-	// Future: add counters like Reconnects, Errors, etc.
+	// Last successful Telegram getUpdates (zero if unknown or not yet)
+	LastTelegramPoll time.Time `json:"last_telegram_poll"`
+
+	// Counters since start (from the metrics package)
+	Dials         uint64 `json:"dials"`
+	DialErrors    uint64 `json:"dial_errors"`
+	Reconnects    uint64 `json:"reconnects"`
+	Notifications uint64 `json:"notifications"`
+	AlertsSent    uint64 `json:"alerts_sent"`
+	SendFailures  uint64 `json:"telegram_send_failures"`
 }
 
 // Snapshot gathers a point-in-time report. It does not block for long operations.
 func (h *Health) Snapshot(ctx context.Context) Report {
-	tracked, open, dropped := h.tm.Stats()
+	stats := h.tm.Stats()
+	conns := h.tm.Conns()
 
 	var persistedCount int
 	if h.st != nil {
@@ -51,11 +86,114 @@ func (h *Health) Snapshot(ctx context.Context) Report {
 		}
 	}
 
+	var lastPoll time.Time
+	if h.lastPoll != nil {
+		lastPoll = h.lastPoll()
+	}
+
 	return Report{
 		GeneratedAt:      time.Now().UTC(),
-		Tracked:          tracked,
-		Open:             open,
-		Dropped:          append([]string(nil), dropped...), // defensive copy
+		Tracked:          stats.Tracked,
+		Open:             stats.Open,
+		Dropped:          append([]string(nil), stats.Dropped...), // defensive copy
+		Subscribers:      stats.Subscribers,
+		Flapping:         flapping(stats.Subscribers, maxFlapping),
+		Connected:        h.tm.Connected(),
+		Failed:           h.tm.Failures(),
+		Conns:            len(conns),
+		Connections:      conns,
+		Endpoints:        h.tm.Endpoints(),
 		TrackedPersisted: persistedCount,
+		LastTelegramPoll: lastPoll,
+		Dials:            metrics.Dials.Value(),
+		DialErrors:       metrics.DialErrors.Value(),
+		Reconnects:       metrics.Reconnects.Value(),
+		Notifications:    metrics.Notifications.Value(),
+		AlertsSent:       metrics.AlertsSent.Value(),
+		SendFailures:     metrics.SendFailures.Value(),
+	}
+}
+
+// Scoped returns the part of r about wallets: per-wallet lists and
+// counters are narrowed to them, and the connection and endpoint details
+// (shared by every chat) are left out. Counters since start stay global.
+func (r Report) Scoped(wallets []string) Report {
+	in := make(map[string]bool, len(wallets))
+	for _, a := range wallets {
+		in[a] = true
+	}
+
+	var dropped []string
+	for _, a := range r.Dropped {
+		if in[a] {
+			dropped = append(dropped, a)
+		}
+	}
+	var failed []tracker.Failure
+	for _, f := range r.Failed {
+		if in[f.Addr] {
+			failed = append(failed, f)
+		}
+	}
+	var subs []tracker.SubscriberStats
+	open, connected := 0, 0
+	for _, s := range r.Subscribers {
+		if !in[s.Addr] {
+			continue
+		}
+		subs = append(subs, s)
+		if s.Open {
+			open++
+		}
+		if s.Connected {
+			connected++
+		}
+	}
+
+	r.Tracked = len(subs)
+	r.Open = open
+	r.Connected = connected
+	r.Dropped = dropped
+	r.Failed = failed
+	r.Subscribers = subs
+	r.Flapping = flapping(subs, maxFlapping)
+	r.Connections = nil
+	r.Endpoints = nil
+	r.TrackedPersisted = len(wallets)
+	return r
+}
+
+// RegisterMetrics exports the subscription gauges (from Manager.Stats) on r.
+func (h *Health) RegisterMetrics(r *metrics.Registry) {
+	r.GaugeFunc("solwatch_subscriptions_tracked", "Wallets with a subscriber in memory.", func() float64 {
+		return float64(h.tm.Stats().Tracked)
+	})
+	r.GaugeFunc("solwatch_subscriptions_open", "Subscriptions acknowledged by the RPC node.", func() float64 {
+		return float64(h.tm.Stats().Open)
+	})
+	r.GaugeFunc("solwatch_subscriptions_dropped", "Subscriptions that should be open but are not.", func() float64 {
+		return float64(len(h.tm.Stats().Dropped))
+	})
+	r.GaugeFunc("solwatch_subscriptions_failed", "Subscriptions given up on after a fatal error.", func() float64 {
+		return float64(len(h.tm.Failures()))
+	})
+}
+
+// maxFlapping caps Report.Flapping.
+const maxFlapping = 5
+
+// flapping returns up to n subscribers that lost their subscription at
+// least once, most disconnects first.
+func flapping(subs []tracker.SubscriberStats, n int) []tracker.SubscriberStats {
+	var out []tracker.SubscriberStats
+	for _, s := range subs {
+		if s.Disconnects > 0 {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Disconnects > out[j].Disconnects })
+	if len(out) > n {
+		out = out[:n]
 	}
+	return out
 }
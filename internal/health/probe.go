@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// snapshotTimeout bounds a probe; a snapshot that takes longer means
+	// the tracker is wedged (e.g. stuck on its lock).
+	snapshotTimeout = 2 * time.Second
+	// mismatchGrace tolerates memory and store disagreeing briefly, as they
+	// do in the middle of a /track or /untrack.
+	mismatchGrace = time.Minute
+)
+
+// ProbeConfig holds the readiness thresholds.
+type ProbeConfig struct {
+	MaxDroppedRatio float64       // dropped/tracked above this is not ready
+	PollStale       time.Duration // no Telegram poll for this long is not ready
+}
+
+// probeResult is the JSON body of /healthz and /readyz.
+type probeResult struct {
+	Status  string   `json:"status"` // "ok" or "fail"
+	Reasons []string `json:"reasons,omitempty"`
+	Report
+}
+
+// Liveness serves /healthz: it fails only if no snapshot can be taken in
+// time, i.e. the process is wedged and a restart is the fix.
+func (h *Health) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := h.snapshotWithin(r.Context(), snapshotTimeout)
+		if !ok {
+			writeProbe(w, probeResult{Status: "fail", Reasons: []string{"snapshot timed out"}})
+			return
+		}
+		writeProbe(w, probeResult{Status: "ok", Report: rep})
+	})
+}
+
+// Readiness serves /readyz: it fails while too many subscriptions are
+// dropped, memory and store disagree, or Telegram polling has stalled.
+func (h *Health) Readiness(cfg ProbeConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := h.snapshotWithin(r.Context(), snapshotTimeout)
+		if !ok {
+			writeProbe(w, probeResult{Status: "fail", Reasons: []string{"snapshot timed out"}})
+			return
+		}
+		res := probeResult{Status: "ok", Report: rep}
+		if res.Reasons = h.notReady(rep, cfg); len(res.Reasons) > 0 {
+			res.Status = "fail"
+		}
+		writeProbe(w, res)
+	})
+}
+
+// snapshotWithin runs Snapshot with a deadline. On timeout the snapshot
+// goroutine is left to finish on its own.
+func (h *Health) snapshotWithin(ctx context.Context, d time.Duration) (Report, bool) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	ch := make(chan Report, 1)
+	go func() { ch <- h.Snapshot(ctx) }()
+	select {
+	case rep := <-ch:
+		return rep, true
+	case <-ctx.Done():
+		return Report{}, false
+	}
+}
+
+// notReady lists why rep does not pass readiness (nil if it does).
+func (h *Health) notReady(rep Report, cfg ProbeConfig) []string {
+	var reasons []string
+	if rep.Tracked > 0 && cfg.MaxDroppedRatio > 0 {
+		if ratio := float64(len(rep.Dropped)) / float64(rep.Tracked); ratio > cfg.MaxDroppedRatio {
+			reasons = append(reasons, fmt.Sprintf("%d of %d subscriptions dropped (%.0f%% > %.0f%%)",
+				len(rep.Dropped), rep.Tracked, ratio*100, cfg.MaxDroppedRatio*100))
+		}
+	}
+
+	h.mu.Lock()
+	if rep.Tracked == rep.TrackedPersisted {
+		h.mismatchSince = time.Time{}
+	} else if h.mismatchSince.IsZero() {
+		h.mismatchSince = rep.GeneratedAt
+	}
+	since := h.mismatchSince
+	h.mu.Unlock()
+	if !since.IsZero() && rep.GeneratedAt.Sub(since) >= mismatchGrace {
+		reasons = append(reasons, fmt.Sprintf("%d wallets in memory but %d in store (for %s)",
+			rep.Tracked, rep.TrackedPersisted, rep.GeneratedAt.Sub(since).Round(time.Second)))
+	}
+
+	if h.lastPoll != nil && cfg.PollStale > 0 {
+		switch {
+		case rep.LastTelegramPoll.IsZero():
+			reasons = append(reasons, "telegram polling has not started")
+		case rep.GeneratedAt.Sub(rep.LastTelegramPoll) > cfg.PollStale:
+			reasons = append(reasons, fmt.Sprintf("no telegram poll for %s", rep.GeneratedAt.Sub(rep.LastTelegramPoll).Round(time.Second)))
+		}
+	}
+	return reasons
+}
+
+func writeProbe(w http.ResponseWriter, res probeResult) {
+	w.Header().Set("Content-Type", "application/json")
+	if res.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(res)
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotReady(t *testing.T) {
+	cfg := ProbeConfig{MaxDroppedRatio: 0.5, PollStale: time.Minute}
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	report := func(at time.Duration, tracked, stored, dropped int, poll time.Duration) Report {
+		return Report{
+			GeneratedAt:      t0.Add(at),
+			Tracked:          tracked,
+			TrackedPersisted: stored,
+			Dropped:          make([]string, dropped),
+			LastTelegramPoll: t0.Add(at - poll),
+		}
+	}
+
+	// Steps share one Health: the mismatch grace spans snapshots.
+	h := &Health{lastPoll: time.Now}
+	steps := []struct {
+		name string
+		rep  Report
+		want []string // substrings, one per expected reason
+	}{
+		{"ready", report(0, 4, 4, 2, time.Second), nil},
+		{"dropped ratio", report(0, 4, 4, 3, time.Second), []string{"3 of 4 subscriptions dropped"}},
+		{"nothing tracked", report(0, 0, 0, 0, time.Second), nil},
+		{"mismatch starts", report(0, 4, 5, 0, time.Second), nil},
+		{"mismatch within grace", report(mismatchGrace-time.Second, 4, 5, 0, time.Second), nil},
+		{"mismatch past grace", report(mismatchGrace, 4, 5, 0, time.Second), []string{"4 wallets in memory but 5 in store"}},
+		{"mismatch resolved", report(mismatchGrace+time.Second, 5, 5, 0, time.Second), nil},
+		{"mismatch again: grace restarts", report(2*mismatchGrace, 5, 6, 0, time.Second), nil},
+		{"stale poll", report(2*mismatchGrace, 6, 6, 0, 2*time.Minute), []string{"no telegram poll for 2m0s"}},
+		{"poll at the limit", report(2*mismatchGrace, 6, 6, 0, time.Minute), nil},
+		{"mismatch starts once more", report(2*mismatchGrace, 4, 6, 0, time.Second), nil},
+		{"everything", report(3*mismatchGrace, 4, 6, 4, time.Hour), []string{"4 of 4", "in store", "no telegram poll"}},
+	}
+	for _, st := range steps {
+		got := h.notReady(st.rep, cfg)
+		if len(got) != len(st.want) {
+			t.Errorf("%s: reasons %q, want %d", st.name, got, len(st.want))
+			continue
+		}
+		for i, w := range st.want {
+			if !strings.Contains(got[i], w) {
+				t.Errorf("%s: reason %q, want it to mention %q", st.name, got[i], w)
+			}
+		}
+	}
+
+	// Polling that never started fails; without a poller the check is off.
+	rep := report(0, 1, 1, 0, 0)
+	rep.LastTelegramPoll = time.Time{}
+	if got := h.notReady(rep, cfg); len(got) != 1 || !strings.Contains(got[0], "not started") {
+		t.Errorf("no poll yet: reasons %q", got)
+	}
+	if got := (&Health{}).notReady(rep, cfg); len(got) != 0 {
+		t.Errorf("no poller: reasons %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+var logger = logging.Component("history")
+
+// compactEvery is how often retention is enforced.
+const compactEvery = time.Hour
+
+// Store is the minimal interface we need from the store.
+type Store interface {
+	AppendHistory(ctx context.Context, wallet string, at time.Time, data []byte) error
+	PruneHistory(ctx context.Context, before time.Time, maxPerWallet int) (int, error)
+}
+
+// Recorder writes every wallet event from the tracker bus to the store and
+// periodically prunes entries past the retention window.
+type Recorder struct {
+	st           Store
+	events       *tracker.BusSubscription
+	retention    time.Duration // 0 = keep forever
+	maxPerWallet int           // 0 = unlimited
+}
+
+// New subscribes a Recorder to bus. History must not lose events silently
+// under load, so the subscription blocks (bounded) rather than dropping.
+func New(bus *tracker.Bus, st Store, retention time.Duration, maxPerWallet int) *Recorder {
+	return &Recorder{
+		st:           st,
+		events:       bus.Subscribe("history", 1024, tracker.Block),
+		retention:    retention,
+		maxPerWallet: maxPerWallet,
+	}
+}
+
+// Run records events and compacts until ctx is canceled.
+func (r *Recorder) Run(ctx context.Context) {
+	defer r.events.Close()
+
+	r.compact(ctx)
+	tick := time.NewTicker(compactEvery)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			r.compact(ctx)
+		case e, ok := <-r.events.C():
+			if !ok {
+				return
+			}
+			r.record(ctx, e)
+		}
+	}
+}
+
+func (r *Recorder) record(ctx context.Context, e tracker.Event) {
+	if e.Wallet == "" {
+		return // endpoint-level alerts belong to no wallet
+	}
+	// Keep entries small: the raw push and program logs are only useful live.
+	e.Raw = nil
+	if e.Tx != nil {
+		tx := *e.Tx
+		tx.Logs = nil
+		e.Tx = &tx
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Error("encode", "wallet", e.Wallet, "error", err)
+		return
+	}
+	if err := r.st.AppendHistory(ctx, e.Wallet, e.Time, data); err != nil && ctx.Err() == nil {
+		logger.Error("append", "wallet", e.Wallet, "error", err)
+	}
+}
+
+func (r *Recorder) compact(ctx context.Context) {
+	var before time.Time
+	if r.retention > 0 {
+		before = time.Now().Add(-r.retention)
+	}
+	if before.IsZero() && r.maxPerWallet <= 0 {
+		return
+	}
+	n, err := r.st.PruneHistory(ctx, before, r.maxPerWallet)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("prune", "error", err)
+		}
+		return
+	}
+	if n > 0 {
+		logger.Info("pruned", "entries", n)
+	}
+}
+
+// Decode parses an entry written by the Recorder back into an Event.
+func Decode(data []byte) (tracker.Event, error) {
+	var e tracker.Event
+	err := json.Unmarshal(data, &e)
+	return e, err
+}
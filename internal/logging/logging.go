@@ -0,0 +1,142 @@
+// Package logging configures the process-wide log/slog logger: a text or
+// JSON handler on stderr, a level that can be changed at runtime, and
+// redaction of secrets in every message and string attribute.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// level is shared by every handler Setup installs, so SetLevel takes
+// effect immediately.
+var level slog.LevelVar
+
+// Options configures Setup.
+type Options struct {
+	Level  string // debug|info|warn|error (default info)
+	Format string // text|json (default text)
+
+	// Redact rewrites secrets out of messages and string or error
+	// attributes (e.g. API keys in URLs). Optional.
+	Redact func(string) string
+
+	Output io.Writer // default os.Stderr
+}
+
+// Setup installs the slog default logger described by o. Output of the
+// standard log package (including third-party libraries) goes through the
+// same handler at info level.
+func Setup(o Options) error {
+	if err := SetLevel(o.Level); err != nil {
+		return err
+	}
+	out := o.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	ho := &slog.HandlerOptions{Level: &level}
+	if o.Redact != nil {
+		ho.ReplaceAttr = func(_ []string, a slog.Attr) slog.Attr {
+			return redactAttr(a, o.Redact)
+		}
+	}
+
+	var h slog.Handler
+	switch strings.ToLower(strings.TrimSpace(o.Format)) {
+	case "", "text":
+		h = slog.NewTextHandler(out, ho)
+	case "json":
+		h = slog.NewJSONHandler(out, ho)
+	default:
+		return fmt.Errorf("unknown log format %q (want text|json)", o.Format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// SetLevel changes the minimum level of the default logger; an empty
+// name means info.
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level returns the current level name (debug|info|warn|error).
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// ParseLevel accepts debug|info|warn|error (case-insensitive; empty is info).
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug|info|warn|error)", name)
+}
+
+// redactAttr applies redact to string values and to errors, which often
+// wrap a request URL.
+func redactAttr(a slog.Attr, redact func(string) string) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Component returns a logger that tags records with component=name and
+// always writes through the current default logger, so it can be created
+// in a package-level var before Setup runs.
+func Component(name string) *slog.Logger {
+	return slog.New(defaultHandler{}).With("component", name)
+}
+
+// defaultHandler forwards to slog.Default's handler at log time, replaying
+// the WithAttrs/WithGroup calls made on it.
+type defaultHandler struct {
+	wrap []func(slog.Handler) slog.Handler
+}
+
+func (h defaultHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, l)
+}
+
+func (h defaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	next := slog.Default().Handler()
+	for _, w := range h.wrap {
+		next = w(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h defaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h defaultHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h defaultHandler) with(w func(slog.Handler) slog.Handler) defaultHandler {
+	return defaultHandler{wrap: append(append([]func(slog.Handler) slog.Handler(nil), h.wrap...), w)}
+}
@@ -0,0 +1,22 @@
+package metrics
+
+// Default is the registry served on /metrics.
+var Default = NewRegistry()
+
+// Instruments updated across the service. Gauges derived from the tracker
+// are registered by health.Health.RegisterMetrics.
+var (
+	Dials         = Default.Counter("solwatch_ws_dials_total", "WebSocket dial attempts.")
+	DialErrors    = Default.Counter("solwatch_ws_dial_errors_total", "WebSocket dial attempts that failed.")
+	Reconnects    = Default.Counter("solwatch_ws_reconnects_total", "WebSocket connections re-established after a drop.")
+	Notifications = Default.Counter("solwatch_notifications_total", "Subscription notifications received.")
+	EventsDropped = Default.Counter("solwatch_events_dropped_total", "Events dropped because a wallet's dispatch backlog was full.")
+	AlertsSent    = Default.Counter("solwatch_alerts_sent_total", "Activity alerts delivered to Telegram.")
+	SendFailures  = Default.Counter("solwatch_telegram_send_failures_total", "Failed Telegram send attempts (retried or not).")
+
+	// DeliveryLatency is the time from receiving a notification to Telegram
+	// accepting the alert (rate limiting and retries included).
+	DeliveryLatency = Default.Histogram("solwatch_alert_delivery_seconds",
+		"Time from notification received to alert delivered.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300})
+)
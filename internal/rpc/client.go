@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+)
+
+var logger = logging.Component("rpc")
+
+// Client is a minimal Solana JSON-RPC client over HTTP.
+// It is safe for concurrent use.
+//
+// With several endpoints, requests go to the current one until it fails
+// (transport error, HTTP 429 or 5xx); the request is then retried on the
+// next endpoint, which becomes current if it answers.
+type Client struct {
+	urls []string
+	cur  atomic.Int64 // index into urls
+	http *http.Client
+	id   atomic.Uint64
+}
+
+// New returns a Client for the given HTTP(S) endpoints, in failover order.
+func New(urls ...string) *Client {
+	c := &Client{http: &http.Client{Timeout: 15 * time.Second}}
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			c.urls = append(c.urls, u)
+		}
+	}
+	return c
+}
+
+// Error is a JSON-RPC error object returned by the node.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Call performs one JSON-RPC request and decodes "result" into out.
+func (c *Client) Call(ctx context.Context, method string, params []any, out any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.id.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	if len(c.urls) == 0 {
+		return errors.New(method + ": no rpc endpoint configured")
+	}
+
+	start := int(c.cur.Load())
+	var raw []byte
+	for i := range c.urls {
+		n := (start + i) % len(c.urls)
+		var failover bool
+		raw, failover, err = c.post(ctx, c.urls[n], method, body)
+		if err == nil || !failover || ctx.Err() != nil {
+			if err == nil && n != start {
+				c.cur.CompareAndSwap(int64(start), int64(n))
+			}
+			break
+		}
+		if len(c.urls) > 1 {
+			logger.Warn("endpoint failed, trying the next one", "endpoint", hostOf(c.urls[n]), "method", method, "error", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var env struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return fmt.Errorf("%s: decode: %w", method, err)
+	}
+	if env.Error != nil {
+		return env.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if len(env.Result) == 0 {
+		return errors.New(method + ": empty result")
+	}
+	return json.Unmarshal(env.Result, out)
+}
+
+// post sends one request to endpoint. failover reports whether another
+// endpoint might do better (the request never got a usable answer).
+func (c *Client) post(ctx context.Context, endpoint, method string, body []byte) (raw []byte, failover bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err = io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: read body: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		failover = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, failover, fmt.Errorf("%s: http %d: %s", method, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return raw, false, nil
+}
+
+// hostOf returns the host of a URL, so it can be logged without secrets.
+func hostOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "endpoint"
+	}
+	return u.Host
+}
+
+// SignatureInfo is one entry returned by getSignaturesForAddress.
+type SignatureInfo struct {
+	Signature          string          `json:"signature"`
+	Slot               uint64          `json:"slot"`
+	Err                json.RawMessage `json:"err"`
+	Memo               *string         `json:"memo"`
+	BlockTime          *int64          `json:"blockTime"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// SignaturesOpts are the optional getSignaturesForAddress parameters.
+type SignaturesOpts struct {
+	Limit          int
+	Before         string
+	Until          string
+	MinContextSlot uint64
+	Commitment     string // confirmed|finalized (processed is not supported)
+}
+
+// GetSignaturesForAddress returns signatures for transactions involving
+// addr, newest first.
+func (c *Client) GetSignaturesForAddress(ctx context.Context, addr string, opts SignaturesOpts) ([]SignatureInfo, error) {
+	cfg := map[string]any{}
+	if opts.Limit > 0 {
+		cfg["limit"] = opts.Limit
+	}
+	if opts.Before != "" {
+		cfg["before"] = opts.Before
+	}
+	if opts.Until != "" {
+		cfg["until"] = opts.Until
+	}
+	if opts.MinContextSlot > 0 {
+		cfg["minContextSlot"] = opts.MinContextSlot
+	}
+	if opts.Commitment != "" {
+		cfg["commitment"] = opts.Commitment
+	}
+
+	var out []SignatureInfo
+	if err := c.Call(ctx, "getSignaturesForAddress", []any{addr, cfg}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTransaction returns the raw jsonParsed result of getTransaction for
+// sig, or a nil RawMessage ("null") if the node does not have it yet.
+func (c *Client) GetTransaction(ctx context.Context, sig string) (json.RawMessage, error) {
+	var out json.RawMessage
+	err := c.Call(ctx, "getTransaction", []any{sig, map[string]any{
+		"encoding":                       "jsonParsed",
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "confirmed",
+	}}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
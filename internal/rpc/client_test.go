@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// server answers getSlot with slot, or with status if it is not 200.
+func server(t *testing.T, status int, slot string, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + slot + `}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestCallFailsOver(t *testing.T) {
+	var downHits, upHits atomic.Int32
+	down := server(t, http.StatusServiceUnavailable, "", &downHits)
+	up := server(t, http.StatusOK, "42", &upHits)
+	c := New(down.URL, up.URL)
+
+	for i := 0; i < 3; i++ {
+		var slot uint64
+		if err := c.Call(context.Background(), "getSlot", nil, &slot); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if slot != 42 {
+			t.Fatalf("call %d: slot = %d, want 42", i, slot)
+		}
+	}
+	// The failing endpoint is only tried once; the next one stays current.
+	if downHits.Load() != 1 || upHits.Load() != 3 {
+		t.Errorf("hits: down=%d up=%d, want 1 and 3", downHits.Load(), upHits.Load())
+	}
+}
+
+func TestCallNoFailoverOnClientError(t *testing.T) {
+	var badHits, upHits atomic.Int32
+	bad := server(t, http.StatusBadRequest, "", &badHits)
+	up := server(t, http.StatusOK, "42", &upHits)
+	c := New(bad.URL, up.URL)
+
+	if err := c.Call(context.Background(), "getSlot", nil, nil); err == nil {
+		t.Fatal("want the 400 error")
+	}
+	if upHits.Load() != 0 {
+		t.Errorf("a 400 must not fail over, got %d calls on the next endpoint", upHits.Load())
+	}
+}
+
+func TestCallAllDown(t *testing.T) {
+	var hits atomic.Int32
+	a := server(t, http.StatusTooManyRequests, "", &hits)
+	b := server(t, http.StatusBadGateway, "", &hits)
+	if err := New(a.URL, b.URL).Call(context.Background(), "getSlot", nil, nil); err == nil {
+		t.Fatal("want an error when every endpoint fails")
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, want 2", hits.Load())
+	}
+}
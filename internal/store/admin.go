@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// DBStats is a summary of the database for operators.
+type DBStats struct {
+	Path           string
+	Size           int64 // bytes on disk
+	SchemaVersion  int
+	Wallets        int
+	Cursors        int
+	HistoryWallets int
+	HistoryEntries int
+	Outbox         int // events awaiting delivery
+}
+
+// Stats counts what the database holds.
+func (b *Bolt) Stats(ctx context.Context) (DBStats, error) {
+	select {
+	case <-ctx.Done():
+		return DBStats{}, ctx.Err()
+	default:
+	}
+
+	s := DBStats{Path: b.db.Path()}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		s.Size = tx.Size()
+		var err error
+		if s.SchemaVersion, err = readSchemaVersion(tx); err != nil {
+			return err
+		}
+		if bkt := tx.Bucket([]byte(walletsBucket)); bkt != nil {
+			s.Wallets = bkt.Stats().KeyN
+		}
+		if bkt := tx.Bucket([]byte(cursorsBucket)); bkt != nil {
+			s.Cursors = bkt.Stats().KeyN
+		}
+		if entries, _, err := outboxTx(tx); err == nil {
+			s.Outbox = entries.Stats().KeyN
+		}
+		if root := tx.Bucket([]byte(historyBucket)); root != nil {
+			return root.ForEachBucket(func(k []byte) error {
+				s.HistoryWallets++
+				s.HistoryEntries += root.Bucket(k).Stats().KeyN
+				return nil
+			})
+		}
+		return nil
+	})
+	if fi, e := os.Stat(s.Path); e == nil {
+		s.Size = fi.Size()
+	}
+	return s, err
+}
+
+// Backup writes a consistent copy of the database to dst. It runs in a
+// read transaction, so it is safe while the store is in use.
+func (b *Bolt) Backup(ctx context.Context, dst string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	return b.db.View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(dst, 0o600)
+	})
+}
+
+// compactTxSize bounds each write transaction while compacting.
+const compactTxSize = 64 << 20
+
+// CompactFile rewrites the DB at path without free pages, reclaiming space
+// left by deleted wallets and pruned history. The DB must not be open
+// elsewhere (the file lock makes this fail while solwatch is running).
+// Returns the file size before and after.
+func CompactFile(path string) (before, after int64, err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	before = fi.Size()
+
+	src, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return 0, 0, fmt.Errorf("open %s: %w", path, err)
+	}
+	tmp := path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := bbolt.Open(tmp, 0o600, nil)
+	if err != nil {
+		_ = src.Close()
+		return 0, 0, fmt.Errorf("open %s: %w", tmp, err)
+	}
+
+	err = bbolt.Compact(dst, src, compactTxSize)
+	err = errors.Join(err, dst.Close(), src.Close())
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, 0, fmt.Errorf("compact: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, 0, err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		after = fi.Size()
+	}
+	return before, after, nil
+}
@@ -2,11 +2,13 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	b58 "github.com/mr-tron/base58/base58"
 	"go.etcd.io/bbolt"
@@ -14,6 +16,44 @@ import (
 
 const (
 	walletsBucket = "wallets"
+	cursorsBucket = "cursors"
+)
+
+// ErrNotFound is returned when a wallet is not in the store.
+var ErrNotFound = errors.New("not found")
+
+// WalletRecord is the JSON value stored per address in the "wallets" bucket.
+// Older databases stored a bare RFC3339 timestamp; migration 3 rewrites
+// those, and decodeWalletRecord still accepts both forms.
+type WalletRecord struct {
+	Address   string    `json:"-"` // the bucket key; filled on read
+	AddedAt   time.Time `json:"added_at"`
+	Mode      string    `json:"mode,omitempty"` // subscription mode: account|logs (empty = account)
+	Label     string    `json:"label,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy int64     `json:"created_by,omitempty"` // Telegram user id that added it
+}
+
+// DisplayName renders the wallet for humans: "Alpha Whale (ABCD...WXYZ)",
+// or just "ABCD...WXYZ" when it has no label.
+func (r WalletRecord) DisplayName() string {
+	short := r.Address
+	if len(short) > 8 {
+		short = short[:4] + "..." + short[len(short)-4:]
+	}
+	if r.Label == "" {
+		return short
+	}
+	return r.Label + " (" + short + ")"
+}
+
+// Limits for user-supplied wallet metadata, in characters (runes).
+const (
+	maxLabelLen = 64
+	maxTagLen   = 32
+	maxTags     = 16
+	maxNoteLen  = 500
 )
 
 // Bolt wraps a bbolt DB for storing tracked wallets.
@@ -21,7 +61,8 @@ type Bolt struct {
 	db *bbolt.DB
 }
 
-// NewBolt opens (or creates) a Bolt DB at path and ensures the "wallets" bucket exists.
+// NewBolt opens (or creates) a Bolt DB at path and migrates it to the
+// current schema. A DB written by a newer version is refused (ErrSchemaTooNew).
 func NewBolt(path string) (*Bolt, error) {
 	if strings.TrimSpace(path) == "" {
 		return nil, errors.New("empty DB path")
@@ -34,13 +75,10 @@ func NewBolt(path string) (*Bolt, error) {
 		return nil, fmt.Errorf("open bolt db: %w", err)
 	}
 
-	// Ensure bucket exists.
-	if err := db.Update(func(tx *bbolt.Tx) error {
-		_, e := tx.CreateBucketIfNotExists([]byte(walletsBucket))
-		return e
-	}); err != nil {
+	// Create buckets / upgrade older files (see migrate.go).
+	if err := migrate(db, path); err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("ensure bucket: %w", err)
+		return nil, fmt.Errorf("migrate: %w", err)
 	}
 
 	return &Bolt{db: db}, nil
@@ -55,8 +93,8 @@ func (b *Bolt) Close() error {
 }
 
 // AddWallet inserts the address if not present. Idempotent.
-// Value is an RFC3339 timestamp when it was added.
-func (b *Bolt) AddWallet(ctx context.Context, addr string) error {
+// Value is a WalletRecord carrying the time it was added and by whom.
+func (b *Bolt) AddWallet(ctx context.Context, addr string, createdBy int64) error {
 	addr = strings.TrimSpace(addr)
 	if err := validateSolanaAddress(addr); err != nil {
 		return fmt.Errorf("invalid address: %w", err)
@@ -68,7 +106,10 @@ func (b *Bolt) AddWallet(ctx context.Context, addr string) error {
 	default:
 	}
 
-	now := time.Now().UTC().Format(time.RFC3339Nano)
+	val, err := json.Marshal(WalletRecord{AddedAt: time.Now().UTC(), CreatedBy: createdBy})
+	if err != nil {
+		return err
+	}
 
 	return b.db.Update(func(tx *bbolt.Tx) error {
 		bkt := tx.Bucket([]byte(walletsBucket))
@@ -79,16 +120,140 @@ func (b *Bolt) AddWallet(ctx context.Context, addr string) error {
 			// already present → idempotent success
 			return nil
 		}
-		return bkt.Put([]byte(addr), []byte(now))
+		return bkt.Put([]byte(addr), val)
 	})
 }
 
-// RemoveWallet deletes the address if present. Idempotent.
-func (b *Bolt) RemoveWallet(ctx context.Context, addr string) error {
+// GetWallet returns the stored record for addr, or ErrNotFound.
+func (b *Bolt) GetWallet(ctx context.Context, addr string) (WalletRecord, error) {
 	addr = strings.TrimSpace(addr)
-	if err := validateSolanaAddress(addr); err != nil {
-		return fmt.Errorf("invalid address: %w", err)
+	select {
+	case <-ctx.Done():
+		return WalletRecord{}, ctx.Err()
+	default:
+	}
+
+	var rec WalletRecord
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(walletsBucket))
+		if bkt == nil {
+			return errors.New("wallets bucket missing")
+		}
+		v := bkt.Get([]byte(addr))
+		if v == nil {
+			return ErrNotFound
+		}
+		var e error
+		rec, e = decodeWalletRecord(v)
+		rec.Address = addr
+		return e
+	})
+	return rec, err
+}
+
+// ListWalletRecords returns every wallet record, sorted by address.
+func (b *Bolt) ListWalletRecords(ctx context.Context) ([]WalletRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []WalletRecord
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(walletsBucket))
+		if bkt == nil {
+			return errors.New("wallets bucket missing")
+		}
+		// bbolt iterates keys in byte order, so the result is already sorted.
+		return bkt.ForEach(func(k, v []byte) error {
+			rec, err := decodeWalletRecord(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			rec.Address = string(k)
+			out = append(out, rec)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// SetWalletLabel sets (or, with an empty label, clears) the display label.
+func (b *Bolt) SetWalletLabel(ctx context.Context, addr, label string) error {
+	label = strings.TrimSpace(label)
+	if utf8.RuneCountInString(label) > maxLabelLen {
+		return fmt.Errorf("label longer than %d characters", maxLabelLen)
+	}
+	return b.updateWallet(ctx, addr, func(rec *WalletRecord) error {
+		rec.Label = label
+		return nil
+	})
+}
+
+// AddWalletTag adds a tag (normalized to lowercase, without '#'). Idempotent.
+func (b *Bolt) AddWalletTag(ctx context.Context, addr, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return b.updateWallet(ctx, addr, func(rec *WalletRecord) error {
+		for _, t := range rec.Tags {
+			if t == tag {
+				return nil
+			}
+		}
+		if len(rec.Tags) >= maxTags {
+			return fmt.Errorf("at most %d tags per wallet", maxTags)
+		}
+		rec.Tags = append(rec.Tags, tag)
+		sort.Strings(rec.Tags)
+		return nil
+	})
+}
+
+// RemoveWalletTag removes a tag if present. Idempotent.
+func (b *Bolt) RemoveWalletTag(ctx context.Context, addr, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return b.updateWallet(ctx, addr, func(rec *WalletRecord) error {
+		kept := rec.Tags[:0]
+		for _, t := range rec.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		rec.Tags = kept
+		return nil
+	})
+}
+
+// SetWalletNote sets (or, with empty text, clears) the free-text note.
+func (b *Bolt) SetWalletNote(ctx context.Context, addr, note string) error {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxNoteLen {
+		return fmt.Errorf("note longer than %d characters", maxNoteLen)
 	}
+	return b.updateWallet(ctx, addr, func(rec *WalletRecord) error {
+		rec.Note = note
+		return nil
+	})
+}
+
+// SetWalletMode updates the subscription mode of an existing wallet.
+func (b *Bolt) SetWalletMode(ctx context.Context, addr, mode string) error {
+	return b.updateWallet(ctx, addr, func(rec *WalletRecord) error {
+		rec.Mode = mode
+		return nil
+	})
+}
+
+// updateWallet applies fn to the record of an existing wallet in one
+// transaction. An error from fn aborts the update.
+func (b *Bolt) updateWallet(ctx context.Context, addr string, fn func(rec *WalletRecord) error) error {
+	addr = strings.TrimSpace(addr)
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -100,8 +265,40 @@ func (b *Bolt) RemoveWallet(ctx context.Context, addr string) error {
 		if bkt == nil {
 			return errors.New("wallets bucket missing")
 		}
-		// Delete returns nil whether or not the key existed.
-		return bkt.Delete([]byte(addr))
+		v := bkt.Get([]byte(addr))
+		if v == nil {
+			return ErrNotFound
+		}
+		rec, err := decodeWalletRecord(v)
+		if err != nil {
+			return err
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		val, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(addr), val)
+	})
+}
+
+// RemoveWallet deletes the address for every chat if present. Idempotent.
+// Chats normally use Unwatch instead.
+func (b *Bolt) RemoveWallet(ctx context.Context, addr string) error {
+	addr = strings.TrimSpace(addr)
+	if err := validateSolanaAddress(addr); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return deleteWalletTx(tx, addr)
 	})
 }
 
@@ -132,8 +329,40 @@ func (b *Bolt) ListWallets(ctx context.Context) ([]string, error) {
 	return addrs, nil
 }
 
+// decodeWalletRecord parses a wallets bucket value: either a JSON
+// WalletRecord or a legacy bare RFC3339 timestamp.
+func decodeWalletRecord(v []byte) (WalletRecord, error) {
+	var rec WalletRecord
+	if len(v) > 0 && v[0] == '{' {
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return WalletRecord{}, fmt.Errorf("decode wallet record: %w", err)
+		}
+		return rec, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(v))
+	if err != nil {
+		return WalletRecord{}, fmt.Errorf("decode wallet timestamp: %w", err)
+	}
+	rec.AddedAt = t
+	return rec, nil
+}
+
 // ----- validation helpers -----
 
+// normalizeTag lowercases a tag and strips a leading '#'.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	switch {
+	case tag == "":
+		return "", errors.New("empty tag")
+	case strings.ContainsAny(tag, " \t\r\n"):
+		return "", errors.New("tag contains whitespace")
+	case utf8.RuneCountInString(tag) > maxTagLen:
+		return "", fmt.Errorf("tag longer than %d characters", maxTagLen)
+	}
+	return tag, nil
+}
+
 // validateSolanaAddress ensures the string is a valid base58-encoded 32-byte public key.
 func validateSolanaAddress(addr string) error {
 	if addr == "" {
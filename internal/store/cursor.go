@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// cursor is the JSON value stored per address in the "cursors" bucket:
+// the newest transaction already reported for the wallet.
+type cursor struct {
+	Signature string    `json:"signature"`
+	Slot      uint64    `json:"slot"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoadCursor returns the last processed signature and slot for addr.
+// A wallet without a cursor yields ("", 0, nil).
+func (b *Bolt) LoadCursor(ctx context.Context, addr string) (string, uint64, error) {
+	addr = strings.TrimSpace(addr)
+	select {
+	case <-ctx.Done():
+		return "", 0, ctx.Err()
+	default:
+	}
+
+	var c cursor
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(cursorsBucket))
+		if bkt == nil {
+			return errors.New("cursors bucket missing")
+		}
+		v := bkt.Get([]byte(addr))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &c); err != nil {
+			return fmt.Errorf("decode cursor: %w", err)
+		}
+		return nil
+	})
+	return c.Signature, c.Slot, err
+}
+
+// SaveCursor records sig/slot as the last processed transaction for addr.
+// Cursors never move backwards: an older slot than the stored one is ignored.
+func (b *Bolt) SaveCursor(ctx context.Context, addr, sig string, slot uint64) error {
+	addr = strings.TrimSpace(addr)
+	if sig == "" {
+		return errors.New("empty signature")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	val, err := json.Marshal(cursor{Signature: sig, Slot: slot, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(cursorsBucket))
+		if bkt == nil {
+			return errors.New("cursors bucket missing")
+		}
+		if v := bkt.Get([]byte(addr)); v != nil {
+			var old cursor
+			if json.Unmarshal(v, &old) == nil && old.Slot > slot {
+				return nil
+			}
+		}
+		return bkt.Put([]byte(addr), val)
+	})
+}
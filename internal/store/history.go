@@ -0,0 +1,199 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// historyBucket holds one nested bucket per wallet; keys inside are 8-byte
+// big-endian UnixNano timestamps, so a cursor walks them in time order.
+const historyBucket = "history"
+
+// HistoryEntry is one recorded event. Data is opaque to the store (the
+// caller's JSON encoding of the event).
+type HistoryEntry struct {
+	Wallet string
+	At     time.Time
+	Data   []byte
+}
+
+// AppendHistory records data for wallet at time at. Entries that land on
+// the same nanosecond are nudged forward so none is overwritten.
+func (b *Bolt) AppendHistory(ctx context.Context, wallet string, at time.Time, data []byte) error {
+	wallet = strings.TrimSpace(wallet)
+	if wallet == "" {
+		return errors.New("empty wallet")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(historyBucket))
+		if root == nil {
+			return errors.New("history bucket missing")
+		}
+		bkt, err := root.CreateBucketIfNotExists([]byte(wallet))
+		if err != nil {
+			return err
+		}
+		ns := at.UnixNano()
+		for bkt.Get(historyKey(ns)) != nil {
+			ns++
+		}
+		return bkt.Put(historyKey(ns), data)
+	})
+}
+
+// History returns up to n of wallet's entries, newest first.
+func (b *Bolt) History(ctx context.Context, wallet string, n int) ([]HistoryEntry, error) {
+	wallet = strings.TrimSpace(wallet)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []HistoryEntry
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(historyBucket))
+		if root == nil {
+			return errors.New("history bucket missing")
+		}
+		if bkt := root.Bucket([]byte(wallet)); bkt != nil {
+			out = newestEntries(wallet, bkt, n)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// RecentHistory returns the n newest entries across wallets (all of them
+// if wallets is nil).
+func (b *Bolt) RecentHistory(ctx context.Context, n int, wallets []string) ([]HistoryEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []HistoryEntry
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(historyBucket))
+		if root == nil {
+			return errors.New("history bucket missing")
+		}
+		// The newest n overall are among the newest n of each wallet.
+		if wallets != nil {
+			for _, w := range wallets {
+				if bkt := root.Bucket([]byte(w)); bkt != nil {
+					out = append(out, newestEntries(w, bkt, n)...)
+				}
+			}
+			return nil
+		}
+		return root.ForEachBucket(func(k []byte) error {
+			out = append(out, newestEntries(string(k), root.Bucket(k), n)...)
+			return nil
+		})
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].At.After(out[j].At) })
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out, err
+}
+
+// PruneHistory deletes entries older than before (ignored if zero) and
+// then trims each wallet to its newest maxPerWallet entries (ignored if
+// <= 0). Wallets left with no entries are dropped. Returns how many
+// entries were removed.
+func (b *Bolt) PruneHistory(ctx context.Context, before time.Time, maxPerWallet int) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	removed := 0
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(historyBucket))
+		if root == nil {
+			return errors.New("history bucket missing")
+		}
+		var wallets [][]byte
+		if err := root.ForEachBucket(func(k []byte) error {
+			wallets = append(wallets, append([]byte(nil), k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, w := range wallets {
+			bkt := root.Bucket(w)
+			total := bkt.Stats().KeyN
+			drop := 0
+			if maxPerWallet > 0 && total > maxPerWallet {
+				drop = total - maxPerWallet
+			}
+			var cutoff []byte
+			if !before.IsZero() {
+				cutoff = historyKey(before.UnixNano())
+			}
+
+			// Oldest first: delete while over the cap or older than cutoff.
+			c := bkt.Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.First() {
+				if drop <= 0 && (cutoff == nil || bytes.Compare(k, cutoff) >= 0) {
+					break
+				}
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				drop--
+				total--
+				removed++
+			}
+			if total <= 0 {
+				if err := root.DeleteBucket(w); err != nil {
+					return fmt.Errorf("drop %s: %w", w, err)
+				}
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// newestEntries reads up to n entries from bkt, newest first.
+func newestEntries(wallet string, bkt *bbolt.Bucket, n int) []HistoryEntry {
+	var out []HistoryEntry
+	c := bkt.Cursor()
+	for k, v := c.Last(); k != nil && len(out) < n; k, v = c.Prev() {
+		if len(k) != 8 {
+			continue
+		}
+		out = append(out, HistoryEntry{
+			Wallet: wallet,
+			At:     time.Unix(0, int64(binary.BigEndian.Uint64(k))).UTC(),
+			Data:   append([]byte(nil), v...), // v is only valid inside the tx
+		})
+	}
+	return out
+}
+
+func historyKey(ns int64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(ns))
+	return k
+}
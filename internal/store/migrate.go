@@ -0,0 +1,154 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+)
+
+var logger = logging.Component("store")
+
+// metaBucket holds store-level metadata such as the schema version.
+const (
+	metaBucket       = "meta"
+	schemaVersionKey = "schema_version"
+)
+
+// ErrSchemaTooNew is returned when the DB was written by a newer solwatch.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// migration upgrades the DB from version-1 to version. Migrations run in
+// order inside one transaction; the version is only bumped if all succeed.
+type migration struct {
+	version int
+	name    string
+	apply   func(tx *bbolt.Tx) error
+}
+
+// migrations is append-only: never edit or reorder a released entry.
+var migrations = []migration{
+	{1, "create wallets and cursors buckets", createBuckets(walletsBucket, cursorsBucket)},
+	{2, "create history bucket", createBuckets(historyBucket)},
+	{3, "convert legacy RFC3339 wallet values to JSON records", migrateLegacyWallets},
+	{4, "create watchers bucket", createBuckets(watchersBucket)},
+	{5, "create users and audit buckets", createBuckets(usersBucket, auditBucket)},
+	{6, "create outbox bucket", createOutbox},
+}
+
+// SchemaVersion is the schema version this binary writes.
+var SchemaVersion = migrations[len(migrations)-1].version
+
+// migrate brings the DB at path up to SchemaVersion, backing the file up
+// first when an existing DB is about to change.
+func migrate(db *bbolt.DB, path string) error {
+	var current int
+	var empty bool
+	if err := db.View(func(tx *bbolt.Tx) error {
+		var err error
+		current, err = readSchemaVersion(tx)
+		k, _ := tx.Cursor().First()
+		empty = k == nil
+		return err
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case current > SchemaVersion:
+		return fmt.Errorf("%w (db v%d, binary v%d)", ErrSchemaTooNew, current, SchemaVersion)
+	case current == SchemaVersion:
+		return nil
+	}
+
+	if !empty {
+		backup := fmt.Sprintf("%s.v%d-%s.bak", path, current, time.Now().UTC().Format("20060102T150405Z"))
+		if err := db.View(func(tx *bbolt.Tx) error {
+			return tx.CopyFile(backup, 0o600)
+		}); err != nil {
+			return fmt.Errorf("backup before migration: %w", err)
+		}
+		logger.Info("backed up before migrating", "db", path, "backup", backup)
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			if err := m.apply(tx); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+			logger.Info("applied migration", "version", m.version, "name", m.name)
+		}
+		meta, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(SchemaVersion)))
+	})
+}
+
+// readSchemaVersion returns the stored version; DBs that predate the meta
+// bucket are version 0.
+func readSchemaVersion(tx *bbolt.Tx) (int, error) {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return 0, nil
+	}
+	v := meta.Get([]byte(schemaVersionKey))
+	if v == nil {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("bad schema version %q: %w", v, err)
+	}
+	return n, nil
+}
+
+func createBuckets(names ...string) func(tx *bbolt.Tx) error {
+	return func(tx *bbolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrateLegacyWallets rewrites bare RFC3339 timestamps as WalletRecord JSON.
+func migrateLegacyWallets(tx *bbolt.Tx) error {
+	bkt := tx.Bucket([]byte(walletsBucket))
+	updates := map[string][]byte{}
+	if err := bkt.ForEach(func(k, v []byte) error {
+		if len(v) > 0 && v[0] == '{' {
+			return nil
+		}
+		rec, err := decodeWalletRecord(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		val, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		updates[string(k)] = val
+		return nil
+	}); err != nil {
+		return err
+	}
+	// Bolt forbids writes while iterating, so apply them afterwards.
+	for k, v := range updates {
+		if err := bkt.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
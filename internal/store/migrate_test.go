@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	legacyAddr = "So11111111111111111111111111111111111111112"
+	recordAddr = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+// rawDB creates a Bolt file at path and runs fn on it, without migrating.
+func rawDB(t *testing.T, path string, fn func(tx *bbolt.Tx) error) {
+	t.Helper()
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Update(fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// schemaVersion reads the stored version of the (closed) DB at path.
+func schemaVersion(t *testing.T, path string) int {
+	t.Helper()
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var v int
+	if err := db.View(func(tx *bbolt.Tx) error {
+		var err error
+		v, err = readSchemaVersion(tx)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+	m, err := filepath.Glob(path + ".v*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMigrateFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "solwatch.db")
+	st, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.db.View(func(tx *bbolt.Tx) error {
+		for _, name := range []string{walletsBucket, cursorsBucket, historyBucket, watchersBucket, usersBucket, auditBucket, outboxBucket, metaBucket} {
+			if tx.Bucket([]byte(name)) == nil {
+				t.Errorf("bucket %q missing", name)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+
+	if v := schemaVersion(t, path); v != SchemaVersion {
+		t.Errorf("schema version = %d, want %d", v, SchemaVersion)
+	}
+	if b := backups(t, path); len(b) != 0 {
+		t.Errorf("a new DB needs no backup, got %v", b)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "solwatch.db")
+	added := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rawDB(t, path, func(tx *bbolt.Tx) error {
+		// A v0 file: wallets holding bare RFC3339 timestamps, no meta bucket.
+		bkt, err := tx.CreateBucket([]byte(walletsBucket))
+		if err != nil {
+			return err
+		}
+		if err := bkt.Put([]byte(legacyAddr), []byte(added.Format(time.RFC3339))); err != nil {
+			return err
+		}
+		return bkt.Put([]byte(recordAddr), []byte(`{"added_at":"2024-03-02T00:00:00Z","label":"kept"}`))
+	})
+
+	st, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	rec, err := st.GetWallet(ctx, legacyAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.AddedAt.Equal(added) {
+		t.Errorf("legacy AddedAt = %v, want %v", rec.AddedAt, added)
+	}
+	if err := st.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket([]byte(walletsBucket)).Get([]byte(legacyAddr)); len(v) == 0 || v[0] != '{' {
+			t.Errorf("legacy value not rewritten as JSON: %q", v)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err = st.GetWallet(ctx, recordAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Label != "kept" {
+		t.Errorf("JSON record changed: %+v", rec)
+	}
+	st.Close()
+
+	if v := schemaVersion(t, path); v != SchemaVersion {
+		t.Errorf("schema version = %d, want %d", v, SchemaVersion)
+	}
+	if b := backups(t, path); len(b) != 1 {
+		t.Fatalf("want one backup, got %v", b)
+	}
+
+	// Reopening an up-to-date DB changes nothing.
+	st, err = NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+	if b := backups(t, path); len(b) != 1 {
+		t.Errorf("reopen made another backup: %v", b)
+	}
+}
+
+func TestMigrateFailureKeepsVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "solwatch.db")
+	rawDB(t, path, func(tx *bbolt.Tx) error {
+		bkt, err := tx.CreateBucket([]byte(walletsBucket))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(legacyAddr), []byte("not a timestamp"))
+	})
+
+	if _, err := NewBolt(path); err == nil {
+		t.Fatal("want a migration error")
+	}
+	if v := schemaVersion(t, path); v != 0 {
+		t.Errorf("schema version = %d after a failed migration, want 0", v)
+	}
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte(historyBucket)) != nil {
+			t.Error("earlier migrations were not rolled back")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateTooNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "solwatch.db")
+	rawDB(t, path, func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucket([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(SchemaVersion+1)))
+	})
+
+	if _, err := NewBolt(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("err = %v, want ErrSchemaTooNew", err)
+	}
+}
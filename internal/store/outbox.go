@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// The outbox holds events not yet delivered by the Telegram sink. Entries
+// are ordered by a sequence number; a second bucket maps each idempotency
+// key to its sequence, so the same event is never queued twice.
+const (
+	outboxBucket  = "outbox"
+	outboxEntries = "entries" // 8-byte big-endian sequence -> OutboxEntry JSON
+	outboxKeys    = "keys"    // idempotency key -> sequence
+)
+
+// outboxCountKey (in the meta bucket) holds the number of outbox entries,
+// so PutOutbox can enforce maxOutboxEntries without counting the bucket.
+const outboxCountKey = "outbox_count"
+
+// maxOutboxEntries bounds the outbox during a long Telegram outage; the
+// oldest entries are dropped.
+const maxOutboxEntries = 10000
+
+// OutboxEntry is a pending event. The store does not interpret Data.
+type OutboxEntry struct {
+	Key  string          `json:"key"`
+	At   time.Time       `json:"at"`
+	Data json.RawMessage `json:"data"`
+}
+
+// PutOutbox queues data under the idempotency key. It returns false (and
+// changes nothing) if key is already pending.
+func (b *Bolt) PutOutbox(ctx context.Context, key string, data []byte) (bool, error) {
+	if key == "" {
+		return false, errors.New("empty outbox key")
+	}
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	val, err := json.Marshal(OutboxEntry{Key: key, At: time.Now().UTC(), Data: data})
+	if err != nil {
+		return false, err
+	}
+	added := false
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		entries, keys, err := outboxTx(tx)
+		if err != nil {
+			return err
+		}
+		if keys.Get([]byte(key)) != nil {
+			return nil
+		}
+		n, err := outboxCount(tx, entries)
+		if err != nil {
+			return err
+		}
+		seq, err := entries.NextSequence()
+		if err != nil {
+			return err
+		}
+		var sk [8]byte
+		binary.BigEndian.PutUint64(sk[:], seq)
+		if err := entries.Put(sk[:], val); err != nil {
+			return err
+		}
+		if err := keys.Put([]byte(key), sk[:]); err != nil {
+			return err
+		}
+		added = true
+		n++
+		// Trim in batches so the common case is a single Put.
+		if n > maxOutboxEntries+100 {
+			c := entries.Cursor()
+			for k, v := c.First(); k != nil && n > maxOutboxEntries; k, v = c.First() {
+				var e OutboxEntry
+				if json.Unmarshal(v, &e) == nil {
+					_ = keys.Delete([]byte(e.Key))
+				}
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				n--
+			}
+		}
+		return setOutboxCount(tx, n)
+	})
+	return added, err
+}
+
+// AckOutbox removes the entry for key. Unknown keys are ignored.
+func (b *Bolt) AckOutbox(ctx context.Context, key string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		entries, keys, err := outboxTx(tx)
+		if err != nil {
+			return err
+		}
+		sk := keys.Get([]byte(key))
+		if sk == nil {
+			return nil
+		}
+		n, err := outboxCount(tx, entries)
+		if err != nil {
+			return err
+		}
+		if err := entries.Delete(sk); err != nil {
+			return err
+		}
+		if err := keys.Delete([]byte(key)); err != nil {
+			return err
+		}
+		return setOutboxCount(tx, n-1)
+	})
+}
+
+// ListOutbox returns every pending entry, oldest first.
+func (b *Bolt) ListOutbox(ctx context.Context) ([]OutboxEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []OutboxEntry
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		entries, _, err := outboxTx(tx)
+		if err != nil {
+			return err
+		}
+		return entries.ForEach(func(_, v []byte) error {
+			var e OutboxEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode outbox entry: %w", err)
+			}
+			out = append(out, e)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func outboxTx(tx *bbolt.Tx) (entries, keys *bbolt.Bucket, err error) {
+	root := tx.Bucket([]byte(outboxBucket))
+	if root == nil {
+		return nil, nil, errors.New("outbox bucket missing")
+	}
+	entries, keys = root.Bucket([]byte(outboxEntries)), root.Bucket([]byte(outboxKeys))
+	if entries == nil || keys == nil {
+		return nil, nil, errors.New("outbox bucket incomplete")
+	}
+	return entries, keys, nil
+}
+
+// outboxCount returns the stored entry count. A DB written before the
+// count was kept has none; it is counted once then.
+func outboxCount(tx *bbolt.Tx, entries *bbolt.Bucket) (int, error) {
+	v := tx.Bucket([]byte(metaBucket)).Get([]byte(outboxCountKey))
+	if v == nil {
+		return entries.Stats().KeyN, nil
+	}
+	n, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("bad outbox count %q: %w", v, err)
+	}
+	return n, nil
+}
+
+func setOutboxCount(tx *bbolt.Tx, n int) error {
+	return tx.Bucket([]byte(metaBucket)).Put([]byte(outboxCountKey), []byte(strconv.Itoa(max(n, 0))))
+}
+
+// createOutbox is migration 6.
+func createOutbox(tx *bbolt.Tx) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(outboxBucket))
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{outboxEntries, outboxKeys} {
+		if _, err := root.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
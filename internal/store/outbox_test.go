@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+// storedOutboxCount reads the count PutOutbox and AckOutbox keep.
+func storedOutboxCount(t *testing.T, st *Bolt) int {
+	t.Helper()
+	var n int
+	if err := st.db.View(func(tx *bbolt.Tx) error {
+		entries, _, err := outboxTx(tx)
+		if err != nil {
+			return err
+		}
+		n, err = outboxCount(tx, entries)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOutboxCount(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	for _, k := range []string{"a", "b", "c", "a"} { // "a" twice: a duplicate
+		if _, err := st.PutOutbox(ctx, k, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.AckOutbox(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AckOutbox(ctx, "unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedOutboxCount(t, st); got != 2 {
+		t.Errorf("count = %d, want 2", got)
+	}
+
+	// A DB from before the count was kept counts its entries once.
+	if err := st.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Delete([]byte(outboxCountKey))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.PutOutbox(ctx, "d", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedOutboxCount(t, st); got != 3 {
+		t.Errorf("count after upgrade = %d, want 3", got)
+	}
+	entries, err := st.ListOutbox(ctx)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("ListOutbox = %d entries, %v; want 3", len(entries), err)
+	}
+}
@@ -0,0 +1,433 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.etcd.io/bbolt"
+)
+
+// Format is a wallet set serialization.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// ParseFormat accepts "json" or "csv" (case-insensitive).
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (want json|csv)", s)
+}
+
+// DetectFormat guesses the format of data from its first non-space byte.
+func DetectFormat(data []byte) Format {
+	if t := bytes.TrimSpace(data); len(t) > 0 && (t[0] == '[' || t[0] == '{') {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// ImportMode says what happens to wallets that are not in the file.
+type ImportMode string
+
+const (
+	ImportMerge   ImportMode = "merge"   // keep them; file rows add or update
+	ImportReplace ImportMode = "replace" // remove them; the file becomes the set
+)
+
+// ParseImportMode accepts "merge" or "replace" (case-insensitive).
+func ParseImportMode(s string) (ImportMode, error) {
+	switch m := ImportMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ImportMerge, ImportReplace:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown import mode %q (want merge|replace)", s)
+}
+
+// csvHeader is the column order of CSV exports; imports accept any order.
+var csvHeader = []string{"address", "added_at", "mode", "label", "tags", "note", "created_by"}
+
+// walletRow is the exported shape of one wallet.
+type walletRow struct {
+	Address   string    `json:"address"`
+	AddedAt   time.Time `json:"added_at"`
+	Mode      string    `json:"mode,omitempty"`
+	Label     string    `json:"label,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy int64     `json:"created_by,omitempty"`
+}
+
+// ExportWallets writes the wallets chatID watches (every wallet if chatID
+// is 0) to w in the given format.
+func (b *Bolt) ExportWallets(ctx context.Context, w io.Writer, f Format, chatID int64) error {
+	recs, err := b.ListWalletRecords(ctx)
+	if err != nil {
+		return err
+	}
+	if chatID != 0 {
+		mine, err := b.WatchedBy(ctx, chatID)
+		if err != nil {
+			return err
+		}
+		recs = filterRecords(recs, mine)
+	}
+
+	switch f {
+	case FormatJSON:
+		rows := make([]walletRow, 0, len(recs))
+		for _, r := range recs {
+			rows = append(rows, walletRow(r))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, r := range recs {
+			created := ""
+			if r.CreatedBy != 0 {
+				created = strconv.FormatInt(r.CreatedBy, 10)
+			}
+			if err := cw.Write([]string{
+				r.Address, r.AddedAt.UTC().Format(time.RFC3339), r.Mode, r.Label,
+				strings.Join(r.Tags, " "), r.Note, created,
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// Import row statuses.
+const (
+	RowAdded   = "added"
+	RowUpdated = "updated"
+	RowInvalid = "invalid"
+)
+
+// ImportRow is the outcome of one input row. Line is 1-based (the CSV line
+// or the JSON array position).
+type ImportRow struct {
+	Line    int
+	Address string
+	Status  string
+	Err     string
+}
+
+// ImportReport summarizes an import. Nothing is written when Applied is
+// false (replace mode with invalid rows).
+type ImportReport struct {
+	Rows    []ImportRow
+	Removed []string // replace mode: wallets (of the chat) not present in the file
+	Applied bool
+}
+
+// Count returns how many rows have the given status.
+func (r ImportReport) Count(status string) int {
+	n := 0
+	for _, row := range r.Rows {
+		if row.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ImportWallets reads a wallet set from r and applies it in one transaction.
+// Invalid rows are reported and skipped in merge mode; in replace mode any
+// invalid row aborts the import so a typo cannot wipe a wallet.
+// Existing wallets keep their earliest added_at; other non-empty fields
+// from the file win.
+//
+// With a non-zero chatID the imported wallets are watched by that chat, and
+// replace only drops that chat's other wallets (deleting those nobody else
+// watches). With chatID 0 the import applies to the whole wallet set.
+func (b *Bolt) ImportWallets(ctx context.Context, r io.Reader, f Format, mode ImportMode, chatID int64) (ImportReport, error) {
+	var rep ImportReport
+	rows, err := decodeRows(r, f)
+	if err != nil {
+		return rep, err
+	}
+	select {
+	case <-ctx.Done():
+		return rep, ctx.Err()
+	default:
+	}
+
+	valid := make(map[string]walletRow, len(rows))
+	for i, row := range rows {
+		res := ImportRow{Line: row.line, Address: strings.TrimSpace(row.Address)}
+		if row.err != "" {
+			res.Status, res.Err = RowInvalid, row.err
+		} else if err := checkRow(&rows[i].walletRow); err != nil {
+			res.Status, res.Err = RowInvalid, err.Error()
+		} else if _, dup := valid[res.Address]; dup {
+			res.Status, res.Err = RowInvalid, "duplicate address"
+		} else {
+			valid[res.Address] = rows[i].walletRow
+		}
+		rep.Rows = append(rep.Rows, res)
+	}
+	if mode == ImportReplace && rep.Count(RowInvalid) > 0 {
+		return rep, nil
+	}
+
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(walletsBucket))
+		if bkt == nil {
+			return errors.New("wallets bucket missing")
+		}
+
+		if mode == ImportReplace {
+			var gone []string
+			if err := bkt.ForEach(func(k, _ []byte) error {
+				if _, keep := valid[string(k)]; !keep && (chatID == 0 || watchedTx(tx, string(k), chatID)) {
+					gone = append(gone, string(k))
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			for _, a := range gone {
+				var err error
+				if chatID == 0 {
+					err = deleteWalletTx(tx, a)
+				} else {
+					_, err = unwatchTx(tx, a, chatID)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			rep.Removed = gone
+		}
+
+		for i := range rep.Rows {
+			res := &rep.Rows[i]
+			if res.Status == RowInvalid {
+				continue
+			}
+			in := valid[res.Address]
+			rec := WalletRecord(in)
+			res.Status = RowAdded
+			if v := bkt.Get([]byte(in.Address)); v != nil {
+				old, err := decodeWalletRecord(v)
+				if err != nil {
+					return fmt.Errorf("%s: %w", in.Address, err)
+				}
+				rec = mergeRecord(old, in)
+				res.Status = RowUpdated
+			}
+			val, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := bkt.Put([]byte(in.Address), val); err != nil {
+				return err
+			}
+			if chatID != 0 {
+				if err := watchTx(tx, in.Address, chatID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rep.Removed = nil
+		return rep, err
+	}
+	rep.Applied = true
+	return rep, nil
+}
+
+// filterRecords keeps the records whose address is in addrs.
+func filterRecords(recs []WalletRecord, addrs []string) []WalletRecord {
+	keep := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		keep[a] = true
+	}
+	out := recs[:0]
+	for _, r := range recs {
+		if keep[r.Address] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// mergeRecord overlays the non-empty fields of in onto old.
+func mergeRecord(old WalletRecord, in walletRow) WalletRecord {
+	if !in.AddedAt.IsZero() && (old.AddedAt.IsZero() || in.AddedAt.Before(old.AddedAt)) {
+		old.AddedAt = in.AddedAt
+	}
+	if in.Mode != "" {
+		old.Mode = in.Mode
+	}
+	if in.Label != "" {
+		old.Label = in.Label
+	}
+	if len(in.Tags) > 0 {
+		old.Tags = in.Tags
+	}
+	if in.Note != "" {
+		old.Note = in.Note
+	}
+	if old.CreatedBy == 0 {
+		old.CreatedBy = in.CreatedBy
+	}
+	return old
+}
+
+// checkRow validates and normalizes one input row in place.
+func checkRow(r *walletRow) error {
+	r.Address = strings.TrimSpace(r.Address)
+	if err := validateSolanaAddress(r.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	r.Mode = strings.ToLower(strings.TrimSpace(r.Mode))
+	switch r.Mode {
+	case "", "account", "logs":
+	default:
+		return fmt.Errorf("unknown mode %q (want account|logs)", r.Mode)
+	}
+	r.Label = strings.TrimSpace(r.Label)
+	if utf8.RuneCountInString(r.Label) > maxLabelLen {
+		return fmt.Errorf("label longer than %d characters", maxLabelLen)
+	}
+	if len(r.Tags) > maxTags {
+		return fmt.Errorf("more than %d tags", maxTags)
+	}
+	tags := r.Tags[:0]
+	for _, t := range r.Tags {
+		t, err := normalizeTag(t)
+		if err != nil {
+			return fmt.Errorf("tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	r.Tags = tags
+	r.Note = strings.TrimSpace(r.Note)
+	if utf8.RuneCountInString(r.Note) > maxNoteLen {
+		return fmt.Errorf("note longer than %d characters", maxNoteLen)
+	}
+	if r.AddedAt.IsZero() {
+		r.AddedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// lineRow is a decoded input row plus its position for the report and
+// any field that failed to parse.
+type lineRow struct {
+	walletRow
+	line int
+	err  string
+}
+
+func decodeRows(r io.Reader, f Format) ([]lineRow, error) {
+	switch f {
+	case FormatJSON:
+		var rows []walletRow
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("decode json: %w", err)
+		}
+		out := make([]lineRow, len(rows))
+		for i, row := range rows {
+			out[i] = lineRow{walletRow: row, line: i + 1}
+		}
+		return out, nil
+
+	case FormatCSV:
+		return decodeCSV(r)
+	}
+	return nil, fmt.Errorf("unknown format %q", f)
+}
+
+// decodeCSV reads rows under a header line naming the columns (at least
+// "address", in any order). Without a header the first column is the address.
+func decodeCSV(r io.Reader) ([]lineRow, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	recs, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("decode csv: %w", err)
+	}
+	if len(recs) == 0 {
+		return nil, nil
+	}
+
+	col := map[string]int{"address": 0}
+	first := 0
+	for _, name := range recs[0] {
+		if strings.EqualFold(strings.TrimSpace(name), "address") {
+			col = map[string]int{}
+			for i, name := range recs[0] {
+				col[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			first = 1
+			break
+		}
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	var out []lineRow
+	for i, rec := range recs[first:] {
+		row := walletRow{
+			Address: get(rec, "address"),
+			Mode:    get(rec, "mode"),
+			Label:   get(rec, "label"),
+			Tags:    strings.Fields(get(rec, "tags")),
+			Note:    get(rec, "note"),
+		}
+		lr := lineRow{walletRow: row, line: first + i + 1}
+		if strings.TrimSpace(row.Address) == "" && len(rec) == 1 {
+			continue // blank line
+		}
+		if v := strings.TrimSpace(get(rec, "added_at")); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				lr.err = fmt.Sprintf("added_at %q is not RFC3339", v)
+			}
+			lr.AddedAt = t
+		}
+		if v := strings.TrimSpace(get(rec, "created_by")); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				lr.err = fmt.Sprintf("created_by %q is not an integer", v)
+			}
+			lr.CreatedBy = n
+		}
+		out = append(out, lr)
+	}
+	return out, nil
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const thirdAddr = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+
+func newTestStore(t *testing.T) *Bolt {
+	t.Helper()
+	st, err := NewBolt(filepath.Join(t.TempDir(), "solwatch.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newTestStore(t)
+	for _, a := range []string{legacyAddr, recordAddr} {
+		if err := src.AddWallet(ctx, a, 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.SetWalletMode(ctx, legacyAddr, "logs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetWalletLabel(ctx, legacyAddr, "hot, wallet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AddWalletTag(ctx, legacyAddr, "#cex"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetWalletNote(ctx, recordAddr, "multi\nline"); err != nil {
+		t.Fatal(err)
+	}
+	want, err := src.ListWalletRecords(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		want[i].AddedAt = want[i].AddedAt.Truncate(time.Second) // CSV keeps seconds
+	}
+
+	for _, f := range []Format{FormatJSON, FormatCSV} {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := src.ExportWallets(ctx, &buf, f, 0); err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectFormat(buf.Bytes()); got != f {
+				t.Errorf("DetectFormat = %s, want %s", got, f)
+			}
+			dst := newTestStore(t)
+			rep, err := dst.ImportWallets(ctx, &buf, f, ImportMerge, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rep.Applied || rep.Count(RowAdded) != 2 {
+				t.Fatalf("report = %+v, want 2 added", rep)
+			}
+			got, err := dst.ListWalletRecords(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i].AddedAt = got[i].AddedAt.Truncate(time.Second)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestImportMerge(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	for _, a := range []string{legacyAddr, recordAddr} {
+		if err := st.AddWallet(ctx, a, 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.SetWalletLabel(ctx, legacyAddr, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddWalletTag(ctx, legacyAddr, "keep"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := st.GetWallet(ctx, legacyAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := "address,added_at,label,mode\n" +
+		legacyAddr + ",2020-01-01T00:00:00Z,new,logs\n" +
+		thirdAddr + ",,,\n" +
+		"not-an-address,,,\n"
+	rep, err := st.ImportWallets(ctx, strings.NewReader(in), FormatCSV, ImportMerge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Applied || rep.Count(RowUpdated) != 1 || rep.Count(RowAdded) != 1 || rep.Count(RowInvalid) != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+	if r := rep.Rows[2]; r.Line != 4 || r.Err == "" {
+		t.Errorf("invalid row reported as %+v", r)
+	}
+
+	rec, err := st.GetWallet(ctx, legacyAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Label != "new" || rec.Mode != "logs" {
+		t.Errorf("file fields did not win: %+v", rec)
+	}
+	if !reflect.DeepEqual(rec.Tags, []string{"keep"}) || rec.CreatedBy != 7 {
+		t.Errorf("fields missing from the file were not kept: %+v", rec)
+	}
+	if want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC); !rec.AddedAt.Equal(want) || !rec.AddedAt.Before(before.AddedAt) {
+		t.Errorf("AddedAt = %v, want the earlier %v", rec.AddedAt, want)
+	}
+	all, err := st.ListWallets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("merge must keep wallets missing from the file, have %v", all)
+	}
+}
+
+func TestImportReplaceWithInvalidRowWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	if err := st.AddWallet(ctx, legacyAddr, 0); err != nil {
+		t.Fatal(err)
+	}
+	in := `[{"address":"` + thirdAddr + `"},{"address":"` + recordAddr + `","mode":"bogus"}]`
+	rep, err := st.ImportWallets(ctx, strings.NewReader(in), FormatJSON, ImportReplace, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Applied || rep.Count(RowInvalid) != 1 {
+		t.Fatalf("report = %+v, want not applied with one invalid row", rep)
+	}
+	all, err := st.ListWallets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, []string{legacyAddr}) {
+		t.Errorf("wallets = %v, want only %s", all, legacyAddr)
+	}
+}
+
+func TestImportReplaceForChat(t *testing.T) {
+	const chatA, chatB = 1, 2
+	ctx := context.Background()
+	st := newTestStore(t)
+	watch := func(addr string, chat int64) {
+		t.Helper()
+		if err := st.AddWallet(ctx, addr, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Watch(ctx, addr, chat); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watch(legacyAddr, chatA) // only A: deleted
+	watch(recordAddr, chatA) // shared with B: kept for B
+	watch(recordAddr, chatB)
+
+	rep, err := st.ImportWallets(ctx, strings.NewReader(thirdAddr+"\n"), FormatCSV, ImportReplace, chatA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(rep.Removed)
+	if !rep.Applied || !reflect.DeepEqual(rep.Removed, []string{recordAddr, legacyAddr}) {
+		t.Fatalf("report = %+v", rep)
+	}
+
+	all, err := st.ListWallets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{thirdAddr, recordAddr}; !reflect.DeepEqual(all, want) {
+		t.Errorf("wallets = %v, want %v", all, want)
+	}
+	mineA, _ := st.WatchedBy(ctx, chatA)
+	mineB, _ := st.WatchedBy(ctx, chatB)
+	if !reflect.DeepEqual(mineA, []string{thirdAddr}) || !reflect.DeepEqual(mineB, []string{recordAddr}) {
+		t.Errorf("watched: A=%v B=%v", mineA, mineB)
+	}
+}
+
+func TestMetadataLimitsCountCharacters(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStore(t)
+	if err := st.AddWallet(ctx, legacyAddr, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 64 two-byte characters: 128 bytes, but within the 64-character limit.
+	label := strings.Repeat("é", maxLabelLen)
+	if err := st.SetWalletLabel(ctx, legacyAddr, label); err != nil {
+		t.Errorf("label of %d characters rejected: %v", maxLabelLen, err)
+	}
+	if err := st.SetWalletLabel(ctx, legacyAddr, label+"é"); err == nil {
+		t.Error("label over the limit accepted")
+	}
+	if err := st.SetWalletNote(ctx, legacyAddr, strings.Repeat("ж", maxNoteLen)); err != nil {
+		t.Errorf("note of %d characters rejected: %v", maxNoteLen, err)
+	}
+	if err := st.AddWalletTag(ctx, legacyAddr, strings.Repeat("ü", maxTagLen)); err != nil {
+		t.Errorf("tag of %d characters rejected: %v", maxTagLen, err)
+	}
+
+	in := `[{"address":"` + recordAddr + `","label":"` + label + `"}]`
+	rep, err := st.ImportWallets(ctx, strings.NewReader(in), FormatJSON, ImportMerge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Count(RowAdded) != 1 {
+		t.Errorf("import rejected a %d-character label: %+v", maxLabelLen, rep.Rows)
+	}
+}
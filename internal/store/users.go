@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	usersBucket = "users" // Telegram user id (8-byte big-endian) -> UserRecord JSON
+	auditBucket = "audit" // 8-byte big-endian UnixNano -> AuditEntry JSON
+)
+
+// maxAuditEntries bounds the audit log; the oldest entries are dropped.
+const maxAuditEntries = 10000
+
+// UserRecord is a granted role. The store does not interpret Role.
+type UserRecord struct {
+	ID        int64     `json:"-"` // the bucket key; filled on read
+	Role      string    `json:"role"`
+	Username  string    `json:"username,omitempty"` // as last seen, informational
+	GrantedBy int64     `json:"granted_by"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// AuditEntry records a security-relevant action or a denied command.
+type AuditEntry struct {
+	At       time.Time `json:"at"`
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username,omitempty"`
+	ChatID   int64     `json:"chat_id"`
+	Command  string    `json:"command"`
+	Allowed  bool      `json:"allowed"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// GetUser returns the record of userID, or ErrNotFound.
+func (b *Bolt) GetUser(ctx context.Context, userID int64) (UserRecord, error) {
+	select {
+	case <-ctx.Done():
+		return UserRecord{}, ctx.Err()
+	default:
+	}
+
+	var rec UserRecord
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(usersBucket))
+		if bkt == nil {
+			return errors.New("users bucket missing")
+		}
+		v := bkt.Get(idKey(userID))
+		if v == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return fmt.Errorf("decode user: %w", err)
+		}
+		rec.ID = userID
+		return nil
+	})
+	return rec, err
+}
+
+// SetUserRole grants role to userID (replacing any previous role).
+func (b *Bolt) SetUserRole(ctx context.Context, userID int64, username, role string, grantedBy int64) error {
+	if userID == 0 || role == "" {
+		return errors.New("empty user id or role")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	val, err := json.Marshal(UserRecord{Role: role, Username: username, GrantedBy: grantedBy, GrantedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(usersBucket))
+		if bkt == nil {
+			return errors.New("users bucket missing")
+		}
+		return bkt.Put(idKey(userID), val)
+	})
+}
+
+// DeleteUser removes userID's role. Returns ErrNotFound if it had none.
+func (b *Bolt) DeleteUser(ctx context.Context, userID int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(usersBucket))
+		if bkt == nil {
+			return errors.New("users bucket missing")
+		}
+		if bkt.Get(idKey(userID)) == nil {
+			return ErrNotFound
+		}
+		return bkt.Delete(idKey(userID))
+	})
+}
+
+// ListUsers returns every user with a role, ordered by id.
+func (b *Bolt) ListUsers(ctx context.Context) ([]UserRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []UserRecord
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(usersBucket))
+		if bkt == nil {
+			return errors.New("users bucket missing")
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			var rec UserRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("decode user: %w", err)
+			}
+			rec.ID = int64(binary.BigEndian.Uint64(k))
+			out = append(out, rec)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// AppendAudit records e (At defaults to now) and trims the log to
+// maxAuditEntries.
+func (b *Bolt) AppendAudit(ctx context.Context, e AuditEntry) error {
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	val, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(auditBucket))
+		if bkt == nil {
+			return errors.New("audit bucket missing")
+		}
+		ns := e.At.UnixNano()
+		for bkt.Get(historyKey(ns)) != nil {
+			ns++
+		}
+		if err := bkt.Put(historyKey(ns), val); err != nil {
+			return err
+		}
+		// Trim in batches so the common case is a single Put.
+		if n := bkt.Stats().KeyN; n > maxAuditEntries+100 {
+			c := bkt.Cursor()
+			for k, _ := c.First(); k != nil && n > maxAuditEntries; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				n--
+			}
+		}
+		return nil
+	})
+}
+
+// RecentAudit returns up to n audit entries, newest first.
+func (b *Bolt) RecentAudit(ctx context.Context, n int) ([]AuditEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []AuditEntry
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(auditBucket))
+		if bkt == nil {
+			return errors.New("audit bucket missing")
+		}
+		c := bkt.Cursor()
+		for k, v := c.Last(); k != nil && len(out) < n; k, v = c.Prev() {
+			var e AuditEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode audit entry: %w", err)
+			}
+			out = append(out, e)
+		}
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// watchersBucket records which chats watch which wallet: one nested bucket
+// per address, keyed by 8-byte big-endian chat id, valued with the RFC3339
+// time the chat started watching. Wallet records themselves (labels, mode,
+// ...) are shared; a wallet is deleted when its last watcher leaves.
+const watchersBucket = "watchers"
+
+// Watch records that chatID watches addr. The wallet must exist
+// (AddWallet). Idempotent.
+func (b *Bolt) Watch(ctx context.Context, addr string, chatID int64) error {
+	addr = strings.TrimSpace(addr)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		if w := tx.Bucket([]byte(walletsBucket)); w == nil || w.Get([]byte(addr)) == nil {
+			return ErrNotFound
+		}
+		return watchTx(tx, addr, chatID)
+	})
+}
+
+// Unwatch removes chatID from addr's watchers. When it was the last one,
+// the wallet (and its cursor) is deleted too and deleted is true.
+func (b *Bolt) Unwatch(ctx context.Context, addr string, chatID int64) (deleted bool, err error) {
+	addr = strings.TrimSpace(addr)
+	if err := validateSolanaAddress(addr); err != nil {
+		return false, fmt.Errorf("invalid address: %w", err)
+	}
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		var e error
+		deleted, e = unwatchTx(tx, addr, chatID)
+		return e
+	})
+	return deleted, err
+}
+
+// Watchers returns the chats watching addr, sorted.
+func (b *Bolt) Watchers(ctx context.Context, addr string) ([]int64, error) {
+	addr = strings.TrimSpace(addr)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var out []int64
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(watchersBucket))
+		if root == nil {
+			return errors.New("watchers bucket missing")
+		}
+		if bkt := root.Bucket([]byte(addr)); bkt != nil {
+			out = chatIDs(bkt)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// WatchedBy returns the addresses chatID watches, sorted.
+func (b *Bolt) WatchedBy(ctx context.Context, chatID int64) ([]string, error) {
+	all, err := b.ListWatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for addr, chats := range all {
+		for _, c := range chats {
+			if c == chatID {
+				out = append(out, addr)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ListWatches returns every watched address with its chats.
+func (b *Bolt) ListWatches(ctx context.Context) (map[string][]int64, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	out := map[string][]int64{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(watchersBucket))
+		if root == nil {
+			return errors.New("watchers bucket missing")
+		}
+		return root.ForEachBucket(func(k []byte) error {
+			if ids := chatIDs(root.Bucket(k)); len(ids) > 0 {
+				out[string(k)] = ids
+			}
+			return nil
+		})
+	})
+	return out, err
+}
+
+// AdoptOrphans makes chatID the watcher of every wallet nobody watches:
+// wallets from databases that predate multi-chat support, or added with
+// the offline CLI. Returns how many were adopted.
+func (b *Bolt) AdoptOrphans(ctx context.Context, chatID int64) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	n := 0
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		wallets := tx.Bucket([]byte(walletsBucket))
+		root := tx.Bucket([]byte(watchersBucket))
+		if wallets == nil || root == nil {
+			return errors.New("wallets or watchers bucket missing")
+		}
+		var orphans []string
+		if err := wallets.ForEach(func(k, _ []byte) error {
+			if bkt := root.Bucket(k); bkt == nil || isEmpty(bkt) {
+				orphans = append(orphans, string(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, addr := range orphans {
+			if err := watchTx(tx, addr, chatID); err != nil {
+				return err
+			}
+		}
+		n = len(orphans)
+		return nil
+	})
+	return n, err
+}
+
+func watchTx(tx *bbolt.Tx, addr string, chatID int64) error {
+	root := tx.Bucket([]byte(watchersBucket))
+	if root == nil {
+		return errors.New("watchers bucket missing")
+	}
+	bkt, err := root.CreateBucketIfNotExists([]byte(addr))
+	if err != nil {
+		return err
+	}
+	if bkt.Get(idKey(chatID)) != nil {
+		return nil
+	}
+	return bkt.Put(idKey(chatID), []byte(time.Now().UTC().Format(time.RFC3339)))
+}
+
+func watchedTx(tx *bbolt.Tx, addr string, chatID int64) bool {
+	root := tx.Bucket([]byte(watchersBucket))
+	if root == nil {
+		return false
+	}
+	bkt := root.Bucket([]byte(addr))
+	return bkt != nil && bkt.Get(idKey(chatID)) != nil
+}
+
+func unwatchTx(tx *bbolt.Tx, addr string, chatID int64) (deleted bool, err error) {
+	root := tx.Bucket([]byte(watchersBucket))
+	if root == nil {
+		return false, errors.New("watchers bucket missing")
+	}
+	if bkt := root.Bucket([]byte(addr)); bkt != nil {
+		if err := bkt.Delete(idKey(chatID)); err != nil {
+			return false, err
+		}
+		if !isEmpty(bkt) {
+			return false, nil
+		}
+	}
+	return true, deleteWalletTx(tx, addr)
+}
+
+// deleteWalletTx removes a wallet with its cursor and watchers.
+func deleteWalletTx(tx *bbolt.Tx, addr string) error {
+	bkt := tx.Bucket([]byte(walletsBucket))
+	if bkt == nil {
+		return errors.New("wallets bucket missing")
+	}
+	// The replay cursor is meaningless once the wallet is gone.
+	if cb := tx.Bucket([]byte(cursorsBucket)); cb != nil {
+		if err := cb.Delete([]byte(addr)); err != nil {
+			return err
+		}
+	}
+	if wb := tx.Bucket([]byte(watchersBucket)); wb != nil && wb.Bucket([]byte(addr)) != nil {
+		if err := wb.DeleteBucket([]byte(addr)); err != nil {
+			return err
+		}
+	}
+	// Delete returns nil whether or not the key existed.
+	return bkt.Delete([]byte(addr))
+}
+
+// isEmpty reports whether bkt has no keys. Unlike Stats it sees writes
+// made earlier in the same transaction.
+func isEmpty(bkt *bbolt.Bucket) bool {
+	k, _ := bkt.Cursor().First()
+	return k == nil
+}
+
+func chatIDs(bkt *bbolt.Bucket) []int64 {
+	var out []int64
+	_ = bkt.ForEach(func(k, _ []byte) error {
+		if len(k) == 8 {
+			out = append(out, int64(binary.BigEndian.Uint64(k)))
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// idKey encodes a Telegram chat or user id as a bucket key.
+func idKey(id int64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
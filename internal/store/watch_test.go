@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestWatchRefcount(t *testing.T) {
+	const alice, bob, admin = 1, -2, 3
+	ctx := context.Background()
+	st := newTestStore(t)
+
+	if err := st.Watch(ctx, legacyAddr, alice); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Watch before AddWallet: %v, want ErrNotFound", err)
+	}
+	for _, a := range []string{legacyAddr, recordAddr} {
+		if err := st.AddWallet(ctx, a, alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, w := range []struct {
+		addr string
+		chat int64
+	}{{legacyAddr, alice}, {legacyAddr, bob}, {legacyAddr, bob}, {recordAddr, bob}} { // bob twice: idempotent
+		if err := st.Watch(ctx, w.addr, w.chat); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.SaveCursor(ctx, legacyAddr, "Sig1", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	checkWatchers := func(addr string, want ...int64) {
+		t.Helper()
+		if got, err := st.Watchers(ctx, addr); err != nil || !slices.Equal(got, want) {
+			t.Errorf("Watchers(%s) = %v, %v; want %v", addr, got, err, want)
+		}
+	}
+	checkWatchers(legacyAddr, bob, alice)
+	if got, err := st.WatchedBy(ctx, bob); err != nil || !slices.Equal(got, []string{recordAddr, legacyAddr}) {
+		t.Errorf("WatchedBy(bob) = %v, %v", got, err)
+	}
+
+	// The wallet stays while anyone watches it.
+	if deleted, err := st.Unwatch(ctx, legacyAddr, alice); err != nil || deleted {
+		t.Fatalf("Unwatch(alice) = %t, %v; want kept", deleted, err)
+	}
+	if deleted, err := st.Unwatch(ctx, legacyAddr, alice); err != nil || deleted {
+		t.Fatalf("Unwatch(alice) again = %t, %v; want kept", deleted, err)
+	}
+	checkWatchers(legacyAddr, bob)
+	if sig, _, err := st.LoadCursor(ctx, legacyAddr); err != nil || sig != "Sig1" {
+		t.Errorf("cursor = %q, %v; want kept", sig, err)
+	}
+
+	// The last watcher takes the wallet and its cursor with it.
+	if deleted, err := st.Unwatch(ctx, legacyAddr, bob); err != nil || !deleted {
+		t.Fatalf("Unwatch(bob) = %t, %v; want deleted", deleted, err)
+	}
+	if _, err := st.GetWallet(ctx, legacyAddr); !errors.Is(err, ErrNotFound) {
+		t.Errorf("wallet after last Unwatch: %v, want ErrNotFound", err)
+	}
+	if sig, _, err := st.LoadCursor(ctx, legacyAddr); err != nil || sig != "" {
+		t.Errorf("cursor after last Unwatch = %q, %v; want none", sig, err)
+	}
+	checkWatchers(legacyAddr)
+
+	// A wallet nobody watches (e.g. added with the CLI) goes to the admin.
+	if err := st.AddWallet(ctx, thirdAddr, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := st.AdoptOrphans(ctx, admin); err != nil || n != 1 {
+		t.Fatalf("AdoptOrphans = %d, %v; want 1", n, err)
+	}
+	all, err := st.ListWatches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]int64{recordAddr: {bob}, thirdAddr: {admin}}
+	if !maps.EqualFunc(all, want, slices.Equal) {
+		t.Errorf("ListWatches = %v, want %v", all, want)
+	}
+}
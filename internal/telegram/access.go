@@ -0,0 +1,256 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/0xsamyy/solwatch/internal/store"
+)
+
+// Role is what a Telegram user may do. Higher roles include lower ones.
+type Role int
+
+const (
+	RoleNone     Role = iota
+	RoleViewer        // /tracked, /health, /history, ...
+	RoleOperator      // + track/untrack and wallet metadata
+	RoleOwner         // + /kill and user management
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleOwner:
+		return "owner"
+	}
+	return "none"
+}
+
+// ParseRole accepts viewer|operator|owner (case-insensitive).
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	case "owner":
+		return RoleOwner, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q (want viewer|operator|owner)", s)
+}
+
+// commandRoles is the minimum role per command. Commands not listed
+// (including unknown ones) need RoleViewer.
+var commandRoles = map[string]Role{
+	"/track":       RoleOperator,
+	"/untrack":     RoleOperator,
+	"/trackmany":   RoleOperator,
+	"/untrackmany": RoleOperator,
+	"/label":       RoleOperator,
+	"/tag":         RoleOperator,
+	"/note":        RoleOperator,
+	"/import":      RoleOperator,
+	"/kill":        RoleOwner,
+	"/grant":       RoleOwner,
+	"/revoke":      RoleOwner,
+	"/users":       RoleOwner,
+	"/audit":       RoleOwner,
+	"/loglevel":    RoleOwner,
+}
+
+// roleOf resolves the sender's role: TELEGRAM_OWNER_ID and the admin
+// chat's user (for a private admin chat) are always owner; explicit grants come from the users bucket;
+// members of the admin chat default to operator and members of the other
+// configured chats to viewer. The highest applicable role wins.
+func (h *Handler) roleOf(ctx context.Context, m *models.Message) Role {
+	return h.roleFor(ctx, senderID(m), m.Chat.ID)
+}
+
+// roleFor is roleOf for user uid writing in chatID.
+func (h *Handler) roleFor(ctx context.Context, uid, chatID int64) Role {
+	if uid != 0 && (uid == h.adminID || uid == h.ownerID) {
+		return RoleOwner
+	}
+
+	role := RoleNone
+	switch {
+	case chatID == h.adminID:
+		role = RoleOperator
+	case h.chats[chatID]:
+		role = RoleViewer
+	}
+	if uid == 0 {
+		return role
+	}
+	rec, err := h.st.GetUser(ctx, uid)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.Error("get user", "user", uid, "error", err)
+		}
+		return role
+	}
+	if granted, err := ParseRole(rec.Role); err == nil && granted > role {
+		role = granted
+	}
+	return role
+}
+
+// authorize checks the sender may run cmd. Denials get a polite reply and
+// an audit entry.
+func (h *Handler) authorize(ctx context.Context, m *models.Message, cmd string) bool {
+	need, ok := commandRoles[cmd]
+	if !ok {
+		need = RoleViewer
+	}
+	have := h.roleOf(ctx, m)
+	if have >= need {
+		return true
+	}
+
+	h.audit(ctx, m, cmd, false, fmt.Sprintf("role %s, needs %s", have, need))
+	if have == RoleNone {
+		h.sendHTML(ctx, m.Chat.ID, "Sorry, you are not authorized to use this bot. Ask an owner to <code>/grant</code> you access.")
+	} else {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("Sorry, <code>%s</code> needs the <b>%s</b> role (you are <b>%s</b>).", escapeHTML(cmd), need, have))
+	}
+	return false
+}
+
+// audit records cmd by the sender of m.
+func (h *Handler) audit(ctx context.Context, m *models.Message, cmd string, allowed bool, detail string) {
+	e := store.AuditEntry{UserID: senderID(m), ChatID: m.Chat.ID, Command: cmd, Allowed: allowed, Detail: detail}
+	if m.From != nil {
+		e.Username = m.From.Username
+	}
+	h.appendAudit(ctx, e)
+}
+
+func (h *Handler) appendAudit(ctx context.Context, e store.AuditEntry) {
+	if err := h.st.AppendAudit(ctx, e); err != nil {
+		logger.Error("audit", "error", err)
+	}
+}
+
+// handleGrant: /grant <user_id> <role>, or /grant <role> in reply to a
+// message from the user.
+func (h *Handler) handleGrant(ctx context.Context, m *models.Message, args []string) {
+	uid, username, rest, ok := targetUser(m, args)
+	if !ok || len(rest) != 1 {
+		h.sendHTML(ctx, m.Chat.ID, "usage: <code>/grant &lt;user_id&gt; viewer|operator|owner</code> (or reply to the user's message with <code>/grant &lt;role&gt;</code>)")
+		return
+	}
+	role, err := ParseRole(rest[0])
+	if err != nil {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("grant failed: <code>%v</code>", err))
+		return
+	}
+	if err := h.st.SetUserRole(ctx, uid, username, role.String(), senderID(m)); err != nil {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("grant failed: <code>%v</code>", err))
+		return
+	}
+	h.audit(ctx, m, "/grant", true, fmt.Sprintf("user %d -> %s", uid, role))
+	h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("granted <b>%s</b> to <code>%d</code>", role, uid))
+}
+
+// handleRevoke: /revoke <user_id>, or /revoke in reply to the user.
+func (h *Handler) handleRevoke(ctx context.Context, m *models.Message, args []string) {
+	uid, _, rest, ok := targetUser(m, args)
+	if !ok || len(rest) != 0 {
+		h.sendHTML(ctx, m.Chat.ID, "usage: <code>/revoke &lt;user_id&gt;</code> (or reply to the user's message with <code>/revoke</code>)")
+		return
+	}
+	if uid == h.adminID || uid == h.ownerID {
+		h.sendHTML(ctx, m.Chat.ID, "the configured admin and owner are always owners")
+		return
+	}
+	if err := h.st.DeleteUser(ctx, uid); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("<code>%d</code> has no granted role", uid))
+			return
+		}
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("revoke failed: <code>%v</code>", err))
+		return
+	}
+	h.audit(ctx, m, "/revoke", true, fmt.Sprintf("user %d", uid))
+	h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("revoked <code>%d</code>", uid))
+}
+
+// handleUsers lists granted roles.
+func (h *Handler) handleUsers(ctx context.Context, chatID int64) {
+	users, err := h.st.ListUsers(ctx)
+	if err != nil {
+		h.sendHTML(ctx, chatID, fmt.Sprintf("users failed: <code>%v</code>", err))
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<b>👥 Users:</b>")
+	if h.adminID > 0 {
+		fmt.Fprintf(&b, "\n• <code>%d</code> owner (configured admin)", h.adminID)
+	}
+	for _, u := range users {
+		fmt.Fprintf(&b, "\n• <code>%d</code> %s", u.ID, escapeHTML(u.Role))
+		if u.Username != "" {
+			b.WriteString(" @" + escapeHTML(u.Username))
+		}
+	}
+	h.sendHTML(ctx, chatID, b.String())
+}
+
+// handleAudit shows the newest audit entries: /audit [n].
+func (h *Handler) handleAudit(ctx context.Context, chatID int64, args []string) {
+	n, ok := historyCount(args, 0)
+	if !ok {
+		h.sendHTML(ctx, chatID, fmt.Sprintf("usage: <code>/audit [n]</code> (n ≤ %d)", maxHistoryList))
+		return
+	}
+	entries, err := h.st.RecentAudit(ctx, n)
+	if err != nil {
+		h.sendHTML(ctx, chatID, fmt.Sprintf("audit failed: <code>%v</code>", err))
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<b>🔐 Audit log:</b>")
+	if len(entries) == 0 {
+		b.WriteString(" empty")
+	}
+	for _, e := range entries {
+		mark := "⛔"
+		if e.Allowed {
+			mark = "✅"
+		}
+		fmt.Fprintf(&b, "\n<code>%s</code> %s <code>%d</code>", e.At.Format("01-02 15:04:05"), mark, e.UserID)
+		if e.Username != "" {
+			b.WriteString(" @" + escapeHTML(e.Username))
+		}
+		fmt.Fprintf(&b, " %s", escapeHTML(e.Command))
+		if e.Detail != "" {
+			b.WriteString(" · " + escapeHTML(e.Detail))
+		}
+	}
+	h.sendHTML(ctx, chatID, b.String())
+}
+
+// targetUser finds the user a /grant or /revoke is about: the author of
+// the replied-to message, else a numeric first argument. The remaining
+// arguments are returned.
+func targetUser(m *models.Message, args []string) (uid int64, username string, rest []string, ok bool) {
+	if r := m.ReplyToMessage; r != nil && r.From != nil {
+		return r.From.ID, r.From.Username, args, true
+	}
+	if len(args) == 0 {
+		return 0, "", nil, false
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id == 0 {
+		return 0, "", nil, false
+	}
+	return id, "", args[1:], true
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xsamyy/solwatch/internal/store"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Role
+		ok   bool
+	}{
+		{"viewer", RoleViewer, true},
+		{" Operator ", RoleOperator, true},
+		{"OWNER", RoleOwner, true},
+		{"none", RoleNone, false},
+		{"", RoleNone, false},
+		{"admin", RoleNone, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRole(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRole(%q) = %v, %v; want %v, ok=%t", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRoleFor(t *testing.T) {
+	const (
+		group   = -1001 // admin chat is a group
+		viewers = -1002 // a configured chat
+		owner   = 7     // TELEGRAM_OWNER_ID
+		granted = 8
+		member  = 9
+	)
+	st, err := store.NewBolt(filepath.Join(t.TempDir(), "solwatch.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.SetUserRole(ctx, granted, "", RoleOwner.String(), owner); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{
+		adminID: group,
+		ownerID: owner,
+		chats:   map[int64]bool{group: true, viewers: true},
+		st:      st,
+	}
+	tests := []struct {
+		name      string
+		uid, chat int64
+		want      Role
+	}{
+		{"owner id anywhere", owner, 12345, RoleOwner},
+		{"owner id in group", owner, group, RoleOwner},
+		{"admin group member", member, group, RoleOperator},
+		{"configured chat member", member, viewers, RoleViewer},
+		{"stranger", member, 12345, RoleNone},
+		{"grant beats chat default", granted, viewers, RoleOwner},
+		{"anonymous sender", 0, group, RoleOperator},
+	}
+	for _, tt := range tests {
+		if got := h.roleFor(ctx, tt.uid, tt.chat); got != tt.want {
+			t.Errorf("%s: roleFor(%d, %d) = %v, want %v", tt.name, tt.uid, tt.chat, got, tt.want)
+		}
+	}
+
+	// A private admin chat's id is its user's id.
+	h = &Handler{adminID: member, chats: map[int64]bool{member: true}, st: st}
+	if got := h.roleFor(ctx, member, member); got != RoleOwner {
+		t.Errorf("private admin chat user: got %v, want owner", got)
+	}
+}
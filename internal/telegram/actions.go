@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/0xsamyy/solwatch/internal/store"
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+// Inline alert buttons carry a short random id ("a:" + 16 hex chars, well
+// under Telegram's 64-byte callback_data limit); the action behind it lives
+// in memory, so buttons of alerts sent before a restart report as expired.
+const (
+	actionPrefix = "a:"
+	actionTTL    = 48 * time.Hour
+	maxActions   = 20000
+	muteFor      = time.Hour
+)
+
+type actionKind int
+
+const (
+	actMute actionKind = iota
+	actUntrack
+	actHistory
+)
+
+func (k actionKind) String() string {
+	switch k {
+	case actMute:
+		return "mute"
+	case actUntrack:
+		return "untrack"
+	case actHistory:
+		return "history"
+	}
+	return "unknown"
+}
+
+// action is what a callback id stands for. It is bound to the chat the
+// alert was sent to.
+type action struct {
+	kind    actionKind
+	wallet  string
+	chatID  int64
+	expires time.Time
+}
+
+// actionRoles is the minimum role to press each button.
+var actionRoles = map[actionKind]Role{
+	actMute:    RoleOperator,
+	actUntrack: RoleOperator,
+	actHistory: RoleViewer,
+}
+
+type muteKey struct {
+	chatID int64
+	wallet string
+}
+
+// actions holds pending button actions and active mutes.
+type actions struct {
+	mu    sync.Mutex
+	byID  map[string]action
+	order []string // insertion order, for eviction
+	mutes map[muteKey]time.Time
+}
+
+func newActions() *actions {
+	return &actions{byID: make(map[string]action), mutes: make(map[muteKey]time.Time)}
+}
+
+// add stores act and returns its callback data.
+func (a *actions) add(act action) string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	act.expires = now.Add(actionTTL)
+	a.byID[id] = act
+	a.order = append(a.order, id)
+	// Drop expired (or, past the cap, oldest) entries from the front.
+	for len(a.order) > 0 {
+		old, ok := a.byID[a.order[0]]
+		if ok && len(a.order) <= maxActions && now.Before(old.expires) {
+			break
+		}
+		delete(a.byID, a.order[0])
+		a.order = a.order[1:]
+	}
+	return actionPrefix + id
+}
+
+func (a *actions) get(data string) (action, bool) {
+	if len(data) <= len(actionPrefix) {
+		return action{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	act, ok := a.byID[data[len(actionPrefix):]]
+	if !ok || time.Now().After(act.expires) {
+		return action{}, false
+	}
+	return act, true
+}
+
+func (a *actions) mute(chatID int64, wallet string, until time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.mutes[muteKey{chatID, wallet}] = until
+}
+
+func (a *actions) unmute(chatID int64, wallet string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.mutes, muteKey{chatID, wallet})
+}
+
+// muted reports whether alerts for wallet are muted in chatID.
+func (a *actions) muted(chatID int64, wallet string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	until, ok := a.mutes[muteKey{chatID, wallet}]
+	if ok && time.Now().After(until) {
+		delete(a.mutes, muteKey{chatID, wallet})
+		return false
+	}
+	return ok
+}
+
+// alertKeyboard builds the buttons under an alert for wallet in chatID.
+func (h *Handler) alertKeyboard(e tracker.Event, chatID int64) *models.InlineKeyboardMarkup {
+	if e.Wallet == "" {
+		return nil
+	}
+	open := models.InlineKeyboardButton{Text: "🔗 Open account", URL: "https://solscan.io/account/" + e.Wallet}
+	if e.Signature != "" {
+		open = models.InlineKeyboardButton{Text: "🔗 Open tx", URL: "https://solscan.io/tx/" + e.Signature}
+	}
+	btn := func(text string, kind actionKind) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: text, CallbackData: h.acts.add(action{kind: kind, wallet: e.Wallet, chatID: chatID})}
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{btn("🔕 Mute 1h", actMute), btn("🗑 Untrack", actUntrack)},
+		{btn("📜 History", actHistory), open},
+	}}
+}
+
+// handleCallback runs the action behind an alert button.
+func (h *Handler) handleCallback(ctx context.Context, q *models.CallbackQuery) {
+	chatID := callbackChat(q)
+	act, ok := h.acts.get(q.Data)
+	if !ok || act.chatID != chatID {
+		h.answer(ctx, q, "This button has expired.")
+		return
+	}
+
+	if need, have := actionRoles[act.kind], h.roleFor(ctx, q.From.ID, chatID); have < need {
+		h.appendAudit(ctx, store.AuditEntry{
+			UserID: q.From.ID, Username: q.From.Username, ChatID: chatID,
+			Command: "button:" + act.kind.String(), Detail: fmt.Sprintf("role %s, needs %s", have, need),
+		})
+		h.answer(ctx, q, fmt.Sprintf("Sorry, this needs the %s role.", need))
+		return
+	}
+
+	switch act.kind {
+	case actMute:
+		h.acts.mute(chatID, act.wallet, time.Now().Add(muteFor))
+		h.answer(ctx, q, "Muted for 1h.")
+		h.sendHTML(ctx, chatID, fmt.Sprintf("🔕 muted <b>%s</b> for 1h", escapeHTML(h.walletName(ctx, act.wallet))))
+
+	case actUntrack:
+		name := h.walletName(ctx, act.wallet)
+		if err := h.untrack(ctx, chatID, act.wallet); err != nil {
+			h.answer(ctx, q, "Untrack failed.")
+			h.sendHTML(ctx, chatID, fmt.Sprintf("untrack failed: <code>%v</code>", err))
+			return
+		}
+		h.answer(ctx, q, "Untracked.")
+		h.sendHTML(ctx, chatID, "untracked <b>"+escapeHTML(name)+"</b>")
+
+	case actHistory:
+		h.answer(ctx, q, "")
+		entries, err := h.st.History(ctx, act.wallet, defaultHistoryList)
+		if err != nil {
+			h.sendHTML(ctx, chatID, fmt.Sprintf("history failed: <code>%v</code>", err))
+			return
+		}
+		h.sendHistory(ctx, chatID, "📜 History of "+escapeHTML(h.walletName(ctx, act.wallet)), entries)
+	}
+}
+
+// answer acknowledges a callback query (stops the button spinner).
+func (h *Handler) answer(ctx context.Context, q *models.CallbackQuery, text string) {
+	_, _ = h.bot.AnswerCallbackQuery(ctx, &tg.AnswerCallbackQueryParams{CallbackQueryID: q.ID, Text: text})
+}
+
+// callbackChat returns the chat of the message a button belongs to.
+func callbackChat(q *models.CallbackQuery) int64 {
+	switch {
+	case q.Message.Message != nil:
+		return q.Message.Message.Chat.ID
+	case q.Message.InaccessibleMessage != nil:
+		return q.Message.InaccessibleMessage.Chat.ID
+	}
+	return 0
+}
@@ -2,61 +2,106 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	tg "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
 	"github.com/0xsamyy/solwatch/internal/health"
+	"github.com/0xsamyy/solwatch/internal/history"
+	"github.com/0xsamyy/solwatch/internal/logging"
+	"github.com/0xsamyy/solwatch/internal/store"
 	"github.com/0xsamyy/solwatch/internal/tracker"
 )
 
+var logger = logging.Component("telegram")
+
 // WalletStore is the minimal interface we need from the persistence layer.
 type WalletStore interface {
-	AddWallet(ctx context.Context, addr string) error
-	RemoveWallet(ctx context.Context, addr string) error
-	ListWallets(ctx context.Context) ([]string, error)
+	AddWallet(ctx context.Context, addr string, createdBy int64) error
+	Watch(ctx context.Context, addr string, chatID int64) error
+	Unwatch(ctx context.Context, addr string, chatID int64) (deleted bool, err error)
+	Watchers(ctx context.Context, addr string) ([]int64, error)
+	WatchedBy(ctx context.Context, chatID int64) ([]string, error)
+	ListWalletRecords(ctx context.Context) ([]store.WalletRecord, error)
+	GetWallet(ctx context.Context, addr string) (store.WalletRecord, error)
+	SetWalletMode(ctx context.Context, addr, mode string) error
+	SetWalletLabel(ctx context.Context, addr, label string) error
+	AddWalletTag(ctx context.Context, addr, tag string) error
+	RemoveWalletTag(ctx context.Context, addr, tag string) error
+	SetWalletNote(ctx context.Context, addr, note string) error
+	History(ctx context.Context, wallet string, n int) ([]store.HistoryEntry, error)
+	RecentHistory(ctx context.Context, n int, wallets []string) ([]store.HistoryEntry, error)
+	ExportWallets(ctx context.Context, w io.Writer, f store.Format, chatID int64) error
+	ImportWallets(ctx context.Context, r io.Reader, f store.Format, mode store.ImportMode, chatID int64) (store.ImportReport, error)
+	GetUser(ctx context.Context, userID int64) (store.UserRecord, error)
+	SetUserRole(ctx context.Context, userID int64, username, role string, grantedBy int64) error
+	DeleteUser(ctx context.Context, userID int64) error
+	ListUsers(ctx context.Context) ([]store.UserRecord, error)
+	AppendAudit(ctx context.Context, e store.AuditEntry) error
+	RecentAudit(ctx context.Context, n int) ([]store.AuditEntry, error)
+	ListOutbox(ctx context.Context) ([]store.OutboxEntry, error)
+	AckOutbox(ctx context.Context, key string) error
 }
 
 // Handler coordinates Telegram <-> tracker/store/health.
 type Handler struct {
 	bot     *tg.Bot
 	adminID int64
+	ownerID int64          // always owner (TELEGRAM_OWNER_ID); 0 if unset
+	chats   map[int64]bool // configured chats (admin included); see roleOf
 	tm      *tracker.Manager
 	st      WalletStore
 	hlth    *health.Health
+	events  *tracker.BusSubscription
+	acts    *actions  // alert buttons and mutes
+	out     *outQueue // rate-limited outbound messages
+	recent  keySet    // event keys already queued (see deliver)
+	dropped uint64    // events.Dropped() at the last redeliverDropped
 
 	// killFn should gracefully shut down the service (cancel context or exit).
 	killFn func()
 }
 
-// New constructs the Telegram Handler and wires Activity notifications.
+// New constructs the Telegram Handler and attaches it to the tracker's event bus.
 // - bot: an initialized *tg.Bot
 // - tm: tracker manager
 // - st: wallet store
 // - hlth: health aggregator
-// - adminID: numeric chat id allowed to control the bot
+// - adminID: numeric chat id that controls the bot (owner; orphan wallets)
+// - chatIDs: further chats whose members may view (grants add more)
+// - ownerID: Telegram user id that is always owner (0: none)
 // - killFn: function invoked on /kill (pass a context cancel from main)
-func New(bot *tg.Bot, tm *tracker.Manager, st WalletStore, hlth *health.Health, adminID int64, killFn func()) *Handler {
+func New(bot *tg.Bot, tm *tracker.Manager, st WalletStore, hlth *health.Health, adminID int64, chatIDs []int64, ownerID int64, killFn func()) *Handler {
+	chats := map[int64]bool{adminID: true}
+	for _, id := range chatIDs {
+		chats[id] = true
+	}
 	h := &Handler{
 		bot:     bot,
 		adminID: adminID,
+		ownerID: ownerID,
+		chats:   chats,
 		tm:      tm,
 		st:      st,
 		hlth:    hlth,
+		acts:    newActions(),
+		out:     newOutQueue(bot),
 		killFn:  killFn,
 	}
 
-	// Bridge tracker -> Telegram (one-line HTML message).
-	tracker.ActivityNotify = func(text string) {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		// Always send to the single admin chat.
-		h.sendHTML(ctx, adminID, text)
-	}
+	// Subscribe now (not in Run) so events published during startup are kept.
+	// Events sit in the outbox until delivered, so apply backpressure rather
+	// than drop; the outbound queue keeps this consumer fast. What is dropped
+	// anyway is redelivered from the outbox (see deliverEvents).
+	h.events = tm.Bus().Subscribe("telegram", 256, tracker.Block)
 
 	return h
 }
@@ -65,28 +110,75 @@ func New(bot *tg.Bot, tm *tracker.Manager, st WalletStore, hlth *health.Health,
 func (h *Handler) Run(ctx context.Context) {
 	// Register a single default handler that processes messages.
 	h.bot.RegisterHandler(tg.HandlerTypeMessageText, "", tg.MatchTypePrefix, func(c context.Context, b *tg.Bot, u *models.Update) {
-		// Only accept messages from the configured admin chat.
+		// Access is checked per command (see authorize).
 		if u.Message == nil {
 			return
 		}
-		if u.Message.Chat.ID != h.adminID {
+		h.handleCommand(c, u.Message)
+	})
+	// Alert buttons (see actions.go).
+	h.bot.RegisterHandler(tg.HandlerTypeCallbackQueryData, actionPrefix, tg.MatchTypePrefix, func(c context.Context, b *tg.Bot, u *models.Update) {
+		if u.CallbackQuery == nil {
 			return
 		}
-		h.handleCommand(c, u.Message)
+		h.handleCallback(c, u.CallbackQuery)
 	})
 
+	// Bridge tracker events -> Telegram, through the outbound queue.
+	go h.out.run(ctx)
+	go h.deliverEvents(ctx)
+
 	// Start long-polling. This blocks until ctx is canceled.
 	h.bot.Start(ctx)
+	h.events.Close()
+	<-h.out.done // flushes what is still queued
+}
+
+// deliverEvents replays the outbox, then delivers every tracker Event,
+// redelivering from the outbox what the bus dropped.
+func (h *Handler) deliverEvents(ctx context.Context) {
+	h.replayOutbox(ctx)
+	tick := time.NewTicker(outboxRetry)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			h.redeliverDropped(ctx)
+		case e, ok := <-h.events.C():
+			if !ok {
+				return
+			}
+			h.deliver(ctx, e)
+		}
+	}
 }
 
 func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 	raw := strings.TrimSpace(m.Text)
+
+	// Strip a bot username suffix from the command word only (e.g.
+	// "/health@mybot" -> "/health"); arguments are left alone.
+	end := len(raw)
+	if i := strings.IndexFunc(raw, unicode.IsSpace); i != -1 {
+		end = i
+	}
+	if i := strings.IndexByte(raw[:end], '@'); i > 0 {
+		raw = raw[:i] + raw[end:]
+	}
 	lower := strings.ToLower(raw)
 
-	// Strip bot username suffix (e.g. "/health@mybot" -> "/health")
-	if idx := strings.IndexRune(lower, '@'); idx != -1 {
-		lower = lower[:idx]
-		raw = raw[:idx]
+	// Only react to commands; groups are also for ordinary conversation.
+	if !strings.HasPrefix(lower, "/") {
+		return
+	}
+	cmd := lower
+	if f := strings.Fields(lower); len(f) > 0 {
+		cmd = f[0]
+	}
+	if !h.authorize(ctx, m, cmd) {
+		return
 	}
 
 	switch {
@@ -94,20 +186,30 @@ func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 		h.replyHelp(ctx, m.Chat.ID)
 
 	case strings.HasPrefix(lower, "/track "):
-		arg := strings.TrimSpace(raw[len("/track"):])
-		if arg == "" {
-			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/track &lt;address&gt;</code>")
+		args := strings.Fields(raw[len("/track"):])
+		if len(args) == 0 || len(args) > 2 {
+			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/track &lt;address&gt; [account|logs]</code>")
 			return
 		}
-		if err := h.st.AddWallet(ctx, arg); err != nil {
+		arg := args[0]
+		var explicit tracker.Mode
+		if len(args) == 2 {
+			md, err := tracker.ParseMode(args[1])
+			if err != nil {
+				h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("track failed: <code>%v</code>", err))
+				return
+			}
+			explicit = md
+		}
+		mode, prev, err := h.trackWallet(ctx, m.Chat.ID, senderID(m), arg, explicit)
+		if err != nil {
 			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("track failed: <code>%v</code>", err))
 			return
 		}
-		if err := h.tm.Track(ctx, arg); err != nil {
-			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("subscriber failed: <code>%v</code>", err))
-			return
+		h.sendHTML(ctx, m.Chat.ID, "tracking <b>"+escapeHTML(arg)+"</b> ("+string(mode)+")")
+		if mode != prev {
+			h.notifyModeChange(ctx, m.Chat.ID, arg, prev, mode)
 		}
-		h.sendHTML(ctx, m.Chat.ID, "tracking <b>"+escapeHTML(arg)+"</b>")
 
 	case strings.HasPrefix(lower, "/untrack "):
 		arg := strings.TrimSpace(raw[len("/untrack"):])
@@ -115,8 +217,7 @@ func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/untrack &lt;address&gt;</code>")
 			return
 		}
-		_ = h.tm.Untrack(ctx, arg)
-		if err := h.st.RemoveWallet(ctx, arg); err != nil {
+		if err := h.untrack(ctx, m.Chat.ID, arg); err != nil {
 			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("untrack failed: <code>%v</code>", err))
 			return
 		}
@@ -130,13 +231,7 @@ func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 		}
 		var added, failed int
 		for _, addr := range args {
-			if err := h.st.AddWallet(ctx, addr); err != nil {
-				failed++
-				continue
-			}
-			if err := h.tm.Track(ctx, addr); err != nil {
-				// rollback from store so DB doesn’t get out of sync
-				_ = h.st.RemoveWallet(ctx, addr)
+			if _, _, err := h.trackWallet(ctx, m.Chat.ID, senderID(m), addr, ""); err != nil {
 				failed++
 				continue
 			}
@@ -153,8 +248,7 @@ func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 		}
 		var removed, failed int
 		for _, addr := range args {
-			_ = h.tm.Untrack(ctx, addr)
-			if err := h.st.RemoveWallet(ctx, addr); err != nil {
+			if err := h.untrack(ctx, m.Chat.ID, addr); err != nil {
 				failed++
 				continue
 			}
@@ -164,41 +258,171 @@ func (h *Handler) handleCommand(ctx context.Context, m *models.Message) {
 		h.sendHTML(ctx, m.Chat.ID, summary)
 
 	case lower == "/tracked":
-		list := h.tm.List()
-		if len(list) == 0 {
+		recs, err := h.st.ListWalletRecords(ctx)
+		if err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("list failed: <code>%v</code>", err))
+			return
+		}
+		recs = h.watchedRecords(ctx, m.Chat.ID, recs)
+		if len(recs) == 0 {
 			h.sendHTML(ctx, m.Chat.ID, "<b>No wallets tracked.</b>")
 			return
 		}
 		var b strings.Builder
 		b.WriteString("<b>📋 Tracked Wallets:</b>\n")
-		for _, a := range list {
-			b.WriteString("• <code>")
-			b.WriteString(escapeHTML(a))
-			b.WriteString("</code>\n")
+		for _, r := range recs {
+			b.WriteString("• ")
+			if r.Label != "" {
+				b.WriteString("<b>" + escapeHTML(r.Label) + "</b> ")
+			}
+			b.WriteString("<code>")
+			b.WriteString(escapeHTML(r.Address))
+			b.WriteString("</code>")
+			if md, _ := h.tm.ModeOf(r.Address); md == tracker.ModeLogs {
+				b.WriteString(" (logs)")
+			}
+			for _, t := range r.Tags {
+				b.WriteString(" #" + escapeHTML(t))
+			}
+			if r.Note != "" {
+				b.WriteString(" — <i>" + escapeHTML(r.Note) + "</i>")
+			}
+			b.WriteString("\n")
 		}
 		h.sendHTML(ctx, m.Chat.ID, b.String())
 
+	case strings.HasPrefix(lower, "/label "):
+		addr, rest := splitAddrArg(raw[len("/label"):])
+		if addr == "" {
+			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/label &lt;address&gt; &lt;name&gt;</code> (no name clears it)")
+			return
+		}
+		if !h.requireWatched(ctx, m.Chat.ID, addr) {
+			return
+		}
+		if err := h.st.SetWalletLabel(ctx, addr, rest); err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("label failed: <code>%v</code>", err))
+			return
+		}
+		h.sendHTML(ctx, m.Chat.ID, "labeled <b>"+escapeHTML(h.walletName(ctx, addr))+"</b>")
+
+	case strings.HasPrefix(lower, "/tag "):
+		addr, rest := splitAddrArg(raw[len("/tag"):])
+		tags := strings.Fields(rest)
+		if addr == "" || len(tags) == 0 {
+			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/tag &lt;address&gt; &lt;tag&gt; [-tag ...]</code> (prefix with - to remove)")
+			return
+		}
+		if !h.requireWatched(ctx, m.Chat.ID, addr) {
+			return
+		}
+		for _, t := range tags {
+			var err error
+			if strings.HasPrefix(t, "-") {
+				err = h.st.RemoveWalletTag(ctx, addr, t[1:])
+			} else {
+				err = h.st.AddWalletTag(ctx, addr, t)
+			}
+			if err != nil {
+				h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("tag %s failed: <code>%v</code>", escapeHTML(t), err))
+				return
+			}
+		}
+		rec, _ := h.st.GetWallet(ctx, addr)
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("tags of <b>%s</b>: <code>%s</code>",
+			escapeHTML(h.walletName(ctx, addr)), escapeHTML(strings.Join(rec.Tags, " "))))
+
+	case strings.HasPrefix(lower, "/note "):
+		addr, rest := splitAddrArg(raw[len("/note"):])
+		if addr == "" {
+			h.sendHTML(ctx, m.Chat.ID, "usage: <code>/note &lt;address&gt; &lt;text&gt;</code> (no text clears it)")
+			return
+		}
+		if !h.requireWatched(ctx, m.Chat.ID, addr) {
+			return
+		}
+		if err := h.st.SetWalletNote(ctx, addr, rest); err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("note failed: <code>%v</code>", err))
+			return
+		}
+		h.sendHTML(ctx, m.Chat.ID, "note saved for <b>"+escapeHTML(h.walletName(ctx, addr))+"</b>")
+
+	case strings.HasPrefix(lower, "/history "):
+		args := strings.Fields(raw[len("/history"):])
+		n, ok := historyCount(args, 1)
+		if len(args) == 0 || !ok {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("usage: <code>/history &lt;address&gt; [n]</code> (n ≤ %d)", maxHistoryList))
+			return
+		}
+		if !h.requireWatched(ctx, m.Chat.ID, args[0]) {
+			return
+		}
+		entries, err := h.st.History(ctx, args[0], n)
+		if err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("history failed: <code>%v</code>", err))
+			return
+		}
+		h.sendHistory(ctx, m.Chat.ID, "📜 History of "+escapeHTML(h.walletName(ctx, args[0])), entries)
+
+	case strings.HasPrefix(lower, "/status "):
+		h.handleStatus(ctx, m.Chat.ID, strings.Fields(raw[len("/status"):]))
+
+	case lower == "/recent" || strings.HasPrefix(lower, "/recent "):
+		args := strings.Fields(raw[len("/recent"):])
+		n, ok := historyCount(args, 0)
+		if !ok {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("usage: <code>/recent [n]</code> (n ≤ %d)", maxHistoryList))
+			return
+		}
+		mine, err := h.st.WatchedBy(ctx, m.Chat.ID)
+		if err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("recent failed: <code>%v</code>", err))
+			return
+		}
+		if len(mine) == 0 {
+			h.sendHistory(ctx, m.Chat.ID, "🕒 Recent activity", nil)
+			return
+		}
+		entries, err := h.st.RecentHistory(ctx, n, mine)
+		if err != nil {
+			h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("recent failed: <code>%v</code>", err))
+			return
+		}
+		h.sendHistory(ctx, m.Chat.ID, "🕒 Recent activity", entries)
+
+	case lower == "/export" || strings.HasPrefix(lower, "/export "):
+		h.handleExport(ctx, m.Chat.ID, strings.Fields(raw[len("/export"):]))
+
+	case lower == "/import" || strings.HasPrefix(lower, "/import "):
+		h.handleImport(ctx, m, strings.Fields(raw[len("/import"):]))
+
 	case lower == "/health":
-		rep := h.hlth.Snapshot(ctx)
-		msg := fmt.Sprintf(
-			"<b>📊 Health Report</b>\n"+
-				"• Tracked (memory): <code>%d</code>\n"+
-				"• Open subs: <code>%d</code>\n"+
-				"• Dropped: <code>%d</code>\n"+
-				"• Tracked (store): <code>%d</code>\n"+
-				"• Time: <code>%s</code>",
-			rep.Tracked, rep.Open, len(rep.Dropped), rep.TrackedPersisted, rep.GeneratedAt.Format(time.RFC3339),
-		)
-		h.sendHTML(ctx, m.Chat.ID, msg)
+		h.handleHealth(ctx, m.Chat.ID)
+
+	case lower == "/grant" || strings.HasPrefix(lower, "/grant "):
+		h.handleGrant(ctx, m, strings.Fields(raw[len("/grant"):]))
+
+	case lower == "/revoke" || strings.HasPrefix(lower, "/revoke "):
+		h.handleRevoke(ctx, m, strings.Fields(raw[len("/revoke"):]))
+
+	case lower == "/users":
+		h.handleUsers(ctx, m.Chat.ID)
+
+	case lower == "/audit" || strings.HasPrefix(lower, "/audit "):
+		h.handleAudit(ctx, m.Chat.ID, strings.Fields(raw[len("/audit"):]))
+
+	case lower == "/loglevel" || strings.HasPrefix(lower, "/loglevel "):
+		h.handleLogLevel(ctx, m, strings.Fields(raw[len("/loglevel"):]))
 
 	case lower == "/kill":
+		h.audit(ctx, m, "/kill", true, "")
 		h.sendHTML(ctx, m.Chat.ID, "shutting down…")
 		go func() {
 			time.Sleep(200 * time.Millisecond)
 			if h.killFn != nil {
 				h.killFn()
 			} else {
-				log.Println("[telegram] killFn not set")
+				logger.Error("killFn not set")
 			}
 		}()
 
@@ -211,38 +435,262 @@ func (h *Handler) replyHelp(ctx context.Context, chatID int64) {
 	help := strings.TrimSpace(`
 <b>🛠 solwatch bot</b>
 
-<b>Commands:</b>
+<b>Viewer:</b>
 • <code>/help</code> – show this help
-• <code>/track &lt;address&gt;</code> – start tracking a wallet
+• <code>/tracked</code> – list tracked wallets
+• <code>/history &lt;address&gt; [n]</code> – recent events of a wallet
+• <code>/recent [n]</code> – latest events across all wallets
+• <code>/export [json|csv]</code> – download the wallet set
+• <code>/health</code> – show counts, dropped, flapping and failed subscriptions
+• <code>/status &lt;address&gt;</code> – connects, disconnects, last error and notification of a wallet
+
+<b>Operator:</b>
+• <code>/track &lt;address&gt; [account|logs]</code> – start tracking a wallet (logs = every tx mentioning it)
 • <code>/untrack &lt;address&gt;</code> – stop tracking a wallet
 • <code>/trackmany &lt;addr1&gt; &lt;addr2&gt; ...</code> – add multiple wallets
 • <code>/untrackmany &lt;addr1&gt; &lt;addr2&gt; ...</code> – remove multiple wallets
-• <code>/tracked</code> – list tracked wallets
-• <code>/health</code> – show counts and dropped subscriptions
+• <code>/label &lt;address&gt; &lt;name&gt;</code> – set a display name
+• <code>/tag &lt;address&gt; &lt;tag&gt; [-tag]</code> – add/remove tags
+• <code>/note &lt;address&gt; &lt;text&gt;</code> – attach a note
+• <code>/import [merge|replace]</code> – reply to an uploaded .json/.csv file
+
+<b>Owner:</b>
+• <code>/grant &lt;user_id&gt; viewer|operator|owner</code> – give a user a role (or reply to them)
+• <code>/revoke &lt;user_id&gt;</code> – remove a user's role (or reply to them)
+• <code>/users</code> – list granted roles
+• <code>/audit [n]</code> – recent denied and privileged commands
+• <code>/loglevel [debug|info|warn|error]</code> – show or change log verbosity
 • <code>/kill</code> – shutdown the service
 `)
 	h.sendHTML(ctx, chatID, help)
 }
 
-// sendHTML sends a Telegram message using HTML parse mode.
-func (h *Handler) sendHTML(ctx context.Context, chatID int64, html string) {
+// History listing limits: default and maximum entries per request, and
+// the message size at which a listing is split (Telegram caps at 4096).
+const (
+	defaultHistoryList = 10
+	maxHistoryList     = 50
+	maxMessageLen      = 3500
+)
+
+// historyCount parses the optional [n] argument at args[i].
+func historyCount(args []string, i int) (int, bool) {
+	if len(args) <= i {
+		return defaultHistoryList, true
+	}
+	if len(args) > i+1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil || n < 1 || n > maxHistoryList {
+		return 0, false
+	}
+	return n, true
+}
+
+// sendHistory renders stored events one per line, newest first, splitting
+// into several messages when needed.
+func (h *Handler) sendHistory(ctx context.Context, chatID int64, title string, entries []store.HistoryEntry) {
+	if len(entries) == 0 {
+		h.sendHTML(ctx, chatID, "<b>"+title+":</b> nothing recorded yet.")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<b>" + title + ":</b>")
+	for _, en := range entries {
+		e, err := history.Decode(en.Data)
+		if err != nil {
+			logger.Warn("history decode", "wallet", en.Wallet, "error", err)
+			continue
+		}
+		line := fmt.Sprintf("\n\n<code>%s</code> %s", en.At.Format("01-02 15:04:05"), renderEvent(e, h.walletName(ctx, en.Wallet)))
+		if b.Len()+len(line) > maxMessageLen {
+			h.sendHTML(ctx, chatID, b.String())
+			b.Reset()
+		}
+		b.WriteString(line)
+	}
+	h.sendHTML(ctx, chatID, b.String())
+}
+
+// maxHealthList caps per-section wallet lists in /health.
+const maxHealthList = 10
+
+// maxDeadList caps the dead letters shown in /health.
+const maxDeadList = 5
+
+// walletName returns "Label (ABCD...WXYZ)" for labeled wallets, else the
+// short address.
+func (h *Handler) walletName(ctx context.Context, addr string) string {
+	if addr == "" {
+		return ""
+	}
+	rec, err := h.st.GetWallet(ctx, addr)
+	if err != nil {
+		return shortAddr(addr)
+	}
+	return rec.DisplayName()
+}
+
+// requireWatched reports whether chatID watches addr, telling the chat
+// when it does not. Wallet metadata and history are scoped to watchers.
+func (h *Handler) requireWatched(ctx context.Context, chatID int64, addr string) bool {
+	for _, o := range h.tm.Owners(addr) {
+		if o == chatID {
+			return true
+		}
+	}
+	h.sendHTML(ctx, chatID, "<code>"+escapeHTML(addr)+"</code> is not tracked in this chat")
+	return false
+}
+
+// watchedRecords keeps the records of wallets chatID watches.
+func (h *Handler) watchedRecords(ctx context.Context, chatID int64, recs []store.WalletRecord) []store.WalletRecord {
+	mine, err := h.st.WatchedBy(ctx, chatID)
+	if err != nil {
+		logger.Error("watched by", "chat", chatID, "error", err)
+		return nil
+	}
+	keep := make(map[string]bool, len(mine))
+	for _, a := range mine {
+		keep[a] = true
+	}
+	out := recs[:0]
+	for _, r := range recs {
+		if keep[r.Address] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// splitAddrArg splits "<addr> rest of text" into its two parts.
+func splitAddrArg(s string) (addr, rest string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t\n"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+// senderID is the Telegram user id of the message author (0 if unknown).
+func senderID(m *models.Message) int64 {
+	if m.From == nil {
+		return 0
+	}
+	return m.From.ID
+}
+
+// untrack stops chatID watching addr (the wallet itself goes once no chat
+// watches it) and lifts any mute.
+func (h *Handler) untrack(ctx context.Context, chatID int64, addr string) error {
+	_ = h.tm.Untrack(ctx, chatID, addr)
+	if _, err := h.st.Unwatch(ctx, addr, chatID); err != nil {
+		return err
+	}
+	h.acts.unmute(chatID, addr)
+	return nil
+}
+
+// trackWallet stores addr, makes chatID one of its watchers and starts its
+// subscriber. The mode is shared by every chat watching the wallet, so it
+// only changes when explicit is set; prev is the mode before.
+//
+// If a step fails, what it did for chatID is undone, so a failed track
+// never leaves a wallet stored or tracked without its watcher.
+func (h *Handler) trackWallet(ctx context.Context, chatID, createdBy int64, addr string, explicit tracker.Mode) (mode, prev tracker.Mode, err error) {
+	watchers, err := h.st.Watchers(ctx, addr)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = h.st.GetWallet(ctx, addr)
+	created := errors.Is(err, store.ErrNotFound)
+	wasWatching := slices.Contains(watchers, chatID)
+
+	if err := h.st.AddWallet(ctx, addr, createdBy); err != nil {
+		return "", "", err
+	}
+	undo := func() {
+		if wasWatching {
+			return
+		}
+		_ = h.tm.Untrack(ctx, chatID, addr)
+		// Unwatch deletes the wallet once nobody watches it: right for one
+		// created here; others keep their watchers. An orphan (no watchers,
+		// e.g. added with the CLI) is left as it was.
+		if created || len(watchers) > 0 {
+			if _, err := h.st.Unwatch(ctx, addr, chatID); err != nil {
+				logger.Warn("track rollback", "wallet", addr, "chat", chatID, "error", err)
+			}
+		}
+	}
+
+	if err := h.st.Watch(ctx, addr, chatID); err != nil {
+		undo()
+		return "", "", err
+	}
+	prev = h.storedMode(ctx, addr)
+	mode = prev
+	if explicit != "" && explicit != prev {
+		if err := h.st.SetWalletMode(ctx, addr, string(explicit)); err != nil {
+			undo()
+			return "", "", err
+		}
+		mode = explicit
+	}
+	if err := h.tm.Track(ctx, chatID, addr, mode); err != nil {
+		if mode != prev {
+			_ = h.st.SetWalletMode(ctx, addr, string(prev))
+		}
+		undo()
+		return "", "", fmt.Errorf("subscriber: %w", err)
+	}
+	return mode, prev, nil
+}
+
+// storedMode returns the persisted subscription mode of addr (default: account).
+func (h *Handler) storedMode(ctx context.Context, addr string) tracker.Mode {
+	rec, err := h.st.GetWallet(ctx, addr)
+	if err != nil {
+		return tracker.ModeAccount
+	}
+	mode, err := tracker.ParseMode(rec.Mode)
+	if err != nil {
+		return tracker.ModeAccount
+	}
+	return mode
+}
+
+// notifyModeChange tells the other chats watching addr that the chat by
+// switched its shared subscription mode.
+func (h *Handler) notifyModeChange(ctx context.Context, by int64, addr string, from, to tracker.Mode) {
+	msg := fmt.Sprintf("<b>%s</b> switched from %s to %s mode by another chat", escapeHTML(addr), from, to)
+	for _, c := range h.tm.Owners(addr) {
+		if c != by {
+			h.sendHTML(ctx, c, msg)
+		}
+	}
+}
+
+// sendHTML queues a Telegram message using HTML parse mode. Delivery is
+// asynchronous (see outQueue); failures end up in the /health dead letters.
+func (h *Handler) sendHTML(_ context.Context, chatID int64, html string) {
+	h.out.push(&outMsg{params: htmlParams(chatID, html)})
+}
+
+// htmlParams builds an HTML message without link previews.
+func htmlParams(chatID int64, html string) *tg.SendMessageParams {
 	disable := true
-	_, err := h.bot.SendMessage(ctx, &tg.SendMessageParams{
+	return &tg.SendMessageParams{
 		ChatID:    chatID,
 		Text:      html,
 		ParseMode: models.ParseModeHTML,
 		LinkPreviewOptions: &models.LinkPreviewOptions{
 			IsDisabled: &disable,
 		},
-	})
-	if err != nil {
-		log.Printf("[telegram] send error: %v", err)
 	}
 }
 
-
-
-
 // escapeHTML escapes minimal characters for safe HTML messages.
 // We rely on Telegram's HTML parse mode; only a tiny subset of tags used (<b>, <code>, <a>).
 func escapeHTML(s string) string {
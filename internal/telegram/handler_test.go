@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/health"
+	"github.com/0xsamyy/solwatch/internal/store"
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+const (
+	walletA = "So11111111111111111111111111111111111111112"
+	walletB = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+func newTestBolt(t *testing.T) *store.Bolt {
+	t.Helper()
+	st, err := store.NewBolt(filepath.Join(t.TempDir(), "solwatch.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// failingWatch is a store whose Watch fails for one chat.
+type failingWatch struct {
+	*store.Bolt
+	chat int64
+}
+
+func (s failingWatch) Watch(ctx context.Context, addr string, chatID int64) error {
+	if chatID == s.chat {
+		return errors.New("disk full")
+	}
+	return s.Bolt.Watch(ctx, addr, chatID)
+}
+
+func TestTrackWalletRollsBack(t *testing.T) {
+	const alice, bob = 1, 2
+	ctx := context.Background()
+	st := newTestBolt(t)
+	tm := tracker.NewManager(nil, "confirmed", 10)
+	defer tm.StopAll()
+	h := &Handler{tm: tm, st: failingWatch{Bolt: st, chat: bob}}
+
+	// A new wallet whose Watch fails is not kept.
+	if _, _, err := h.trackWallet(ctx, bob, bob, walletA, ""); err == nil {
+		t.Fatal("trackWallet: want error")
+	}
+	if _, err := st.GetWallet(ctx, walletA); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("wallet left stored: err=%v", err)
+	}
+	if got := tm.List(); len(got) != 0 {
+		t.Errorf("wallet left tracked: %v", got)
+	}
+
+	// A wallet another chat watches stays as it was.
+	if _, _, err := h.trackWallet(ctx, alice, alice, walletB, tracker.ModeAccount); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.trackWallet(ctx, bob, bob, walletB, ""); err == nil {
+		t.Fatal("trackWallet: want error")
+	}
+	if got, err := st.Watchers(ctx, walletB); err != nil || !slices.Equal(got, []int64{alice}) {
+		t.Errorf("watchers = %v, %v; want [%d]", got, err, alice)
+	}
+	if got := tm.Owners(walletB); !slices.Equal(got, []int64{alice}) {
+		t.Errorf("owners = %v, want [%d]", got, alice)
+	}
+}
+
+func TestHealthReportScopedToChat(t *testing.T) {
+	const admin, alice, bob = -100, 1, 2
+	ctx := context.Background()
+	st := newTestBolt(t)
+	tm := tracker.NewManager(nil, "confirmed", 10)
+	defer tm.StopAll()
+	h := &Handler{adminID: admin, tm: tm, st: st, hlth: health.New(tm, st), out: newOutQueue(nil)}
+	for chat, addr := range map[int64]string{alice: walletA, bob: walletB} {
+		if _, _, err := h.trackWallet(ctx, chat, chat, addr, ""); err != nil {
+			t.Fatal(err)
+		}
+		h.out.dead = append(h.out.dead, DeadLetter{At: time.Now(), ChatID: chat, Err: "blocked by " + addr})
+	}
+
+	// No endpoint: both subscriptions stay dropped.
+	tests := []struct {
+		chat      int64
+		see, hide []string
+	}{
+		{alice, []string{walletA}, []string{walletB}},
+		{bob, []string{walletB}, []string{walletA}},
+		{admin, []string{walletA, walletB}, nil},
+	}
+	for _, tt := range tests {
+		got := h.healthReport(ctx, tt.chat)
+		for _, a := range tt.see {
+			if strings.Count(got, a) != 2 { // dropped link, dead letter
+				t.Errorf("chat %d: want %s twice in\n%s", tt.chat, a, got)
+			}
+		}
+		for _, a := range tt.hide {
+			if strings.Contains(got, a) {
+				t.Errorf("chat %d: sees %s in\n%s", tt.chat, a, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+)
+
+// handleLogLevel: /loglevel shows the current level, /loglevel <level>
+// changes it until the next restart (LOG_LEVEL applies again then).
+func (h *Handler) handleLogLevel(ctx context.Context, m *models.Message, args []string) {
+	if len(args) == 0 {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("log level: <b>%s</b>", logging.Level()))
+		return
+	}
+	if len(args) != 1 {
+		h.sendHTML(ctx, m.Chat.ID, "usage: <code>/loglevel [debug|info|warn|error]</code>")
+		return
+	}
+	prev := logging.Level()
+	if err := logging.SetLevel(args[0]); err != nil {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("loglevel failed: <code>%s</code>", escapeHTML(err.Error())))
+		return
+	}
+	h.audit(ctx, m, "/loglevel", true, prev+" -> "+logging.Level())
+	logger.Info("log level changed", "from", prev, "to", logging.Level(), "user", senderID(m))
+	h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("log level: <b>%s</b> (was %s)", logging.Level(), prev))
+}
@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+// outboxRetry is how often deliverEvents checks for events the bus dropped.
+const outboxRetry = 30 * time.Second
+
+// maxRecentKeys bounds the set of event keys already handed to the queue,
+// which filters events both replayed from the outbox and still on the bus.
+const maxRecentKeys = 4096
+
+// keySet is a bounded set of recent idempotency keys.
+type keySet struct {
+	mu    sync.Mutex
+	keys  map[string]struct{}
+	order []string
+}
+
+// add reports false if key is already in the set.
+func (s *keySet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string]struct{})
+	}
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	s.keys[key] = struct{}{}
+	s.order = append(s.order, key)
+	if len(s.order) > maxRecentKeys {
+		delete(s.keys, s.order[0])
+		s.order = s.order[1:]
+	}
+	return true
+}
+
+// replayOutbox delivers the events persisted but not yet delivered: at
+// startup those of a previous run, later those the bus dropped (see
+// redeliverDropped). Delivery is at-least-once: an alert whose send
+// succeeded just before a crash can arrive twice.
+func (h *Handler) replayOutbox(ctx context.Context) {
+	entries, err := h.st.ListOutbox(ctx)
+	if err != nil {
+		logger.Error("outbox", "error", err)
+		return
+	}
+	if len(entries) > 0 {
+		logger.Info("replaying undelivered events", "count", len(entries))
+	}
+	for _, en := range entries {
+		var e tracker.Event
+		if err := json.Unmarshal(en.Data, &e); err != nil {
+			logger.Error("outbox decode", "key", en.Key, "error", err)
+			h.ack(en.Key)
+			continue
+		}
+		h.deliver(ctx, e)
+	}
+}
+
+// redeliverDropped replays the outbox if the bus dropped events for us since
+// the last call. A dropped event is still pending in the outbox, and the bus
+// does not publish its key again until it is acknowledged.
+func (h *Handler) redeliverDropped(ctx context.Context) {
+	n := h.events.Dropped()
+	if n == h.dropped {
+		return
+	}
+	logger.Warn("event buffer overflowed; redelivering from the outbox", "dropped", n-h.dropped)
+	h.dropped = n
+	h.replayOutbox(ctx)
+}
+
+// deliver renders e and queues it for every chat that watches the wallet
+// (the admin chat for events without a watcher). Chats that muted the
+// wallet are skipped; wallet alerts carry action buttons. The outbox entry
+// is acknowledged once every message is done.
+func (h *Handler) deliver(ctx context.Context, e tracker.Event) {
+	key := e.Key()
+	if !h.recent.add(key) {
+		return
+	}
+
+	sctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	chats := h.tm.Owners(e.Wallet)
+	if len(chats) == 0 {
+		chats = []int64{h.adminID}
+	}
+	var targets []int64
+	for _, id := range chats {
+		if e.Wallet == "" || !h.acts.muted(id, e.Wallet) {
+			targets = append(targets, id)
+		}
+	}
+	if len(targets) == 0 {
+		h.ack(key)
+		return
+	}
+
+	msg := renderEvent(e, h.walletName(sctx, e.Wallet))
+	var left atomic.Int32
+	left.Store(int32(len(targets)))
+	done := func() {
+		if left.Add(-1) == 0 {
+			h.ack(key)
+		}
+	}
+	for _, id := range targets {
+		p := htmlParams(id, msg)
+		if kb := h.alertKeyboard(e, id); kb != nil {
+			p.ReplyMarkup = kb
+		}
+		h.out.push(&outMsg{params: p, since: e.Time, done: done})
+	}
+}
+
+// ack removes a delivered event from the outbox.
+func (h *Handler) ack(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.st.AckOutbox(ctx, key); err != nil {
+		logger.Error("outbox ack", "key", key, "error", err)
+	}
+}
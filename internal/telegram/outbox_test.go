@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+func TestRedeliverDropped(t *testing.T) {
+	ctx := context.Background()
+	st := newTestBolt(t)
+	tm := tracker.NewManager(nil, "confirmed", 10)
+	defer tm.StopAll()
+	bus := tm.Bus()
+	bus.UseOutbox(st)
+	h := &Handler{adminID: -100, tm: tm, st: st, acts: newActions(), out: newOutQueue(nil)}
+	// Stands in for the Block sink once blockTimeout has passed: the second
+	// event does not fit and is dropped.
+	h.events = bus.Subscribe("telegram", 1, tracker.DropNewest)
+	at := time.Now()
+	for slot := uint64(1); slot <= 2; slot++ {
+		bus.Publish(tracker.Event{Wallet: walletA, Kind: tracker.KindAccount, Slot: slot, Time: at,
+			Account: &tracker.AccountChange{Lamports: slot}})
+	}
+	if n := h.events.Dropped(); n != 1 {
+		t.Fatalf("dropped %d, want 1", n)
+	}
+
+	h.deliver(ctx, <-h.events.C())
+	h.redeliverDropped(ctx)
+	if got := h.out.stats().Pending; got != 2 {
+		t.Errorf("queued %d messages, want 2", got)
+	}
+
+	// Nothing new dropped: nothing is queued twice.
+	h.redeliverDropped(ctx)
+	h.replayOutbox(ctx)
+	if got := h.out.stats().Pending; got != 2 {
+		t.Errorf("queued %d messages after replay, want 2", got)
+	}
+}
@@ -0,0 +1,39 @@
+package telegram
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PollClient is the bot's HTTP client (pass it with tg.WithHTTPClient). It
+// records when getUpdates last succeeded, so health probes can tell that
+// long polling has stalled.
+type PollClient struct {
+	client *http.Client
+	last   atomic.Int64 // UnixNano; 0 = never
+}
+
+// NewPollClient returns a client whose requests time out after timeout
+// (use the bot's poll timeout).
+func NewPollClient(timeout time.Duration) *PollClient {
+	return &PollClient{client: &http.Client{Timeout: timeout}}
+}
+
+// Do implements tg.HttpClient.
+func (p *PollClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := p.client.Do(req)
+	if err == nil && resp.StatusCode == http.StatusOK && strings.HasSuffix(req.URL.Path, "/getUpdates") {
+		p.last.Store(time.Now().UnixNano())
+	}
+	return resp, err
+}
+
+// LastPoll is when getUpdates last succeeded (zero if never).
+func (p *PollClient) LastPoll() time.Time {
+	if n := p.last.Load(); n != 0 {
+		return time.Unix(0, n).UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,434 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram/bot"
+
+	"github.com/0xsamyy/solwatch/internal/metrics"
+	"github.com/0xsamyy/solwatch/internal/util"
+)
+
+// Telegram allows about one message per second per chat, 20 per minute in
+// groups and 30 per second overall. The buckets stay a little below that;
+// a 429 still pauses the chat for the retry_after Telegram asks for.
+const (
+	chatRate    = 1.0       // messages/s to a private chat
+	groupRate   = 20.0 / 60 // messages/s to a group (negative chat id)
+	chatBurst   = 3
+	globalRate  = 25.0
+	globalBurst = 25
+
+	maxQueued       = 2000 // pending messages across all chats
+	maxDeadLetters  = 50   // failed messages kept for /health
+	maxSendAttempts = 5    // transient failures before a message is dead
+	flushTimeout    = 5 * time.Second
+)
+
+// DeadLetter is a message that could not be delivered.
+type DeadLetter struct {
+	At     time.Time
+	ChatID int64
+	Text   string // truncated
+	Err    string
+}
+
+// QueueStats summarizes the outbound queue.
+type QueueStats struct {
+	Pending int
+	Sent    uint64
+	Retried uint64
+	Dead    uint64
+}
+
+// bucket is a token bucket; until pauses it after a 429.
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst}
+}
+
+// wait returns how long until a token is available (0 = now).
+func (b *bucket) wait(now time.Time) time.Duration {
+	if now.Before(b.until) {
+		return b.until.Sub(now)
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take() { b.tokens-- }
+
+// idle reports whether the bucket is full again and can be forgotten.
+func (b *bucket) idle(now time.Time) bool {
+	return b.wait(now) == 0 && b.tokens >= b.burst
+}
+
+type outMsg struct {
+	params    *tg.SendMessageParams
+	since     time.Time // alerts: when the notification arrived (for metrics)
+	attempts  int
+	backoff   *util.Backoff // created on the first transient failure
+	notBefore time.Time
+	// done is optional; it runs once the message is sent or dead-lettered
+	// for good (rejected, e.g. bot blocked, maxSendAttempts failures or
+	// dropped for a full queue).
+	done func()
+}
+
+// finish records a delivered (or rejected) message and runs the done hook.
+func (m *outMsg) finish(sent bool) {
+	if sent && !m.since.IsZero() {
+		metrics.AlertsSent.Inc()
+		metrics.DeliveryLatency.Observe(time.Since(m.since).Seconds())
+	}
+	if m.done != nil {
+		m.done()
+	}
+}
+
+func (m *outMsg) chatID() int64 {
+	id, _ := m.params.ChatID.(int64)
+	return id
+}
+
+// outQueue delivers messages in order per chat, round-robin across chats,
+// within per-chat and global rate limits. Memory is bounded by maxQueued
+// and maxDeadLetters.
+type outQueue struct {
+	bot *tg.Bot
+
+	mu      sync.Mutex
+	pending map[int64][]*outMsg
+	order   []int64 // chats with pending messages, in round-robin order
+	n       int
+	chats   map[int64]*bucket
+	global  *bucket
+	dead    []DeadLetter // oldest first
+	sent    uint64
+	retried uint64
+	deadN   uint64
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+func newOutQueue(bot *tg.Bot) *outQueue {
+	return &outQueue{
+		bot:     bot,
+		pending: make(map[int64][]*outMsg),
+		chats:   make(map[int64]*bucket),
+		global:  newBucket(globalRate, globalBurst),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// push queues m. When the queue is full, the oldest message of the same
+// chat (or, if it has none, m) is dropped: dead-lettered and finished, like
+// a message that ran out of attempts.
+func (q *outQueue) push(m *outMsg) {
+	chat := m.chatID()
+
+	var dropped *outMsg
+	q.mu.Lock()
+	if q.n >= maxQueued {
+		dropped = m
+		if list := q.pending[chat]; len(list) > 0 {
+			dropped = list[0]
+			q.pending[chat] = list[1:]
+			q.n--
+		}
+		q.deadLocked(dropped, "queue full")
+	}
+	if dropped != m {
+		if _, ok := q.pending[chat]; !ok { // chats in order have an entry, maybe emptied above
+			q.order = append(q.order, chat)
+		}
+		q.pending[chat] = append(q.pending[chat], m)
+		q.n++
+	}
+	q.mu.Unlock()
+
+	if dropped != nil {
+		dropped.finish(false) // the dead letter is the record; don't leave it pending
+		if dropped == m {
+			return
+		}
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the first message, in round-robin chat order, that may be sent
+// now. Otherwise it returns how long to wait (-1: nothing pending).
+func (q *outQueue) next(now time.Time) (*outMsg, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return nil, -1
+	}
+	if w := q.global.wait(now); w > 0 {
+		return nil, w
+	}
+	wait := time.Duration(-1)
+	for i, chat := range q.order {
+		m := q.pending[chat][0]
+		w := q.bucketLocked(chat).wait(now)
+		if d := m.notBefore.Sub(now); d > w {
+			w = d
+		}
+		if w > 0 {
+			if wait < 0 || w < wait {
+				wait = w
+			}
+			continue
+		}
+
+		q.bucketLocked(chat).take()
+		q.global.take()
+		q.n--
+		if rest := q.pending[chat][1:]; len(rest) > 0 {
+			q.pending[chat] = rest
+			// rotate: this chat goes to the back
+			q.order = append(append(q.order[:i:i], q.order[i+1:]...), chat)
+		} else {
+			delete(q.pending, chat)
+			q.order = append(q.order[:i:i], q.order[i+1:]...)
+		}
+		return m, 0
+	}
+	return nil, wait
+}
+
+// requeue puts m back at the head of its chat, keeping per-chat order.
+func (q *outQueue) requeue(m *outMsg) {
+	chat := m.chatID()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending[chat]) == 0 {
+		q.order = append(q.order, chat)
+	}
+	q.pending[chat] = append([]*outMsg{m}, q.pending[chat]...)
+	q.n++
+}
+
+func (q *outQueue) bucketLocked(chat int64) *bucket {
+	b := q.chats[chat]
+	if b == nil {
+		rate := chatRate
+		if chat < 0 {
+			rate = groupRate
+		}
+		b = newBucket(rate, chatBurst)
+		q.chats[chat] = b
+	}
+	return b
+}
+
+func (q *outQueue) deadLocked(m *outMsg, reason string) {
+	text := m.params.Text
+	if r := []rune(text); len(r) > 120 {
+		text = string(r[:120]) + "…"
+	}
+	if len(q.dead) == maxDeadLetters {
+		q.dead = append(q.dead[:0], q.dead[1:]...)
+	}
+	q.dead = append(q.dead, DeadLetter{At: time.Now().UTC(), ChatID: m.chatID(), Text: text, Err: reason})
+	q.deadN++
+	logger.Warn("message dead", "chat", m.chatID(), "attempt", m.attempts, "error", reason)
+}
+
+// run sends queued messages until ctx is done, then tries to flush what is
+// left for up to flushTimeout (so e.g. the /kill reply still goes out).
+func (q *outQueue) run(ctx context.Context) {
+	defer close(q.done)
+	prune := time.NewTicker(time.Minute)
+	defer prune.Stop()
+
+	for {
+		if ctx.Err() != nil {
+			q.flush()
+			return
+		}
+		m, wait := q.next(time.Now())
+		if m != nil {
+			q.send(ctx, m)
+			continue
+		}
+		var timer <-chan time.Time
+		var t *time.Timer
+		if wait >= 0 {
+			t = time.NewTimer(wait)
+			timer = t.C
+		}
+		select {
+		case <-ctx.Done():
+			q.flush()
+			return
+		case <-q.wake:
+		case <-timer:
+		case <-prune.C:
+			q.pruneBuckets()
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// flush sends what is left without waiting on rate limits or retries.
+// Messages it cannot send are not finished, so their outbox entries stay
+// for the next start.
+func (q *outQueue) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	q.mu.Lock()
+	var left []*outMsg
+	for _, chat := range q.order {
+		left = append(left, q.pending[chat]...)
+	}
+	q.pending = make(map[int64][]*outMsg)
+	q.order = nil
+	q.n = 0
+	q.mu.Unlock()
+
+	for i, m := range left {
+		if ctx.Err() != nil {
+			logger.Warn("shutdown: queued messages not sent", "count", len(left)-i)
+			return
+		}
+		if _, err := q.bot.SendMessage(ctx, m.params); err != nil {
+			metrics.SendFailures.Inc()
+			logger.Warn("send failed", "chat", m.chatID(), "error", err)
+			continue
+		}
+		m.finish(true)
+	}
+}
+
+// send delivers m once, then requeues it (429, transient error) or
+// dead-letters it (permanent error, too many attempts).
+func (q *outQueue) send(ctx context.Context, m *outMsg) {
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	_, err := q.bot.SendMessage(sctx, m.params)
+	cancel()
+	if err == nil {
+		q.mu.Lock()
+		q.sent++
+		q.mu.Unlock()
+		logger.Debug("sent", "chat", m.chatID(), "attempt", m.attempts+1)
+		m.finish(true)
+		return
+	}
+	metrics.SendFailures.Inc()
+	if ctx.Err() != nil {
+		q.requeue(m) // shutting down; flush gets another go
+		return
+	}
+
+	var flood *tg.TooManyRequestsError
+	switch {
+	case errors.As(err, &flood):
+		pause := time.Duration(flood.RetryAfter) * time.Second
+		if pause <= 0 {
+			pause = time.Second
+		}
+		logger.Warn("rate limited", "chat", m.chatID(), "retry_in", pause)
+		q.mu.Lock()
+		q.bucketLocked(m.chatID()).until = time.Now().Add(pause)
+		q.retried++
+		q.mu.Unlock()
+		q.requeue(m)
+
+	case permanent(err):
+		q.mu.Lock()
+		q.deadLocked(m, err.Error())
+		q.mu.Unlock()
+		m.finish(false)
+
+	default:
+		m.attempts++
+		if m.attempts >= maxSendAttempts {
+			q.mu.Lock()
+			q.deadLocked(m, err.Error())
+			q.mu.Unlock()
+			m.finish(false) // the dead letter is the record; don't leave it pending
+			return
+		}
+		if m.backoff == nil {
+			m.backoff = util.NewBackoff(time.Second, 30*time.Second, 2.0, 0.2)
+		}
+		wait := m.backoff.Next()
+		logger.Warn("send failed", "chat", m.chatID(), "attempt", m.attempts, "retry_in", wait, "error", err)
+		m.notBefore = time.Now().Add(wait)
+		q.mu.Lock()
+		q.retried++
+		q.mu.Unlock()
+		q.requeue(m)
+	}
+}
+
+// permanent reports errors that retrying cannot fix.
+func permanent(err error) bool {
+	var migrate *tg.MigrateError
+	return errors.Is(err, tg.ErrorForbidden) || errors.Is(err, tg.ErrorBadRequest) ||
+		errors.Is(err, tg.ErrorUnauthorized) || errors.Is(err, tg.ErrorNotFound) ||
+		errors.As(err, &migrate)
+}
+
+// pruneBuckets forgets the buckets of chats that are idle.
+func (q *outQueue) pruneBuckets() {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for chat, b := range q.chats {
+		if len(q.pending[chat]) == 0 && b.idle(now) {
+			delete(q.chats, chat)
+		}
+	}
+}
+
+// stats returns queue counters.
+func (q *outQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{Pending: q.n, Sent: q.sent, Retried: q.retried, Dead: q.deadN}
+}
+
+// deadLetters returns up to n dead letters for chatID (0: every chat),
+// newest first.
+func (q *outQueue) deadLetters(n int, chatID int64) []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []DeadLetter
+	for i := len(q.dead) - 1; i >= 0 && len(out) < n; i-- {
+		if chatID == 0 || q.dead[i].ChatID == chatID {
+			out = append(out, q.dead[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram/bot"
+)
+
+// fakeBot returns a bot whose API calls are answered by handler.
+func fakeBot(t *testing.T, handler http.HandlerFunc) *tg.Bot {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	b, err := tg.New("123:test", tg.WithServerURL(srv.URL), tg.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// A message that keeps failing is dead-lettered after maxSendAttempts and
+// finished, so its outbox entry does not stay pending.
+func TestSendDeadAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	b := fakeBot(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":500,"description":"Internal Server Error"}`))
+	})
+	q := newOutQueue(b)
+	done := 0
+	m := &outMsg{params: htmlParams(42, "hello"), done: func() { done++ }}
+
+	for i := 0; i < maxSendAttempts; i++ {
+		q.send(context.Background(), m)
+		if i < maxSendAttempts-1 && done != 0 {
+			t.Fatalf("done after %d attempts", i+1)
+		}
+	}
+	if calls != maxSendAttempts {
+		t.Errorf("calls = %d, want %d", calls, maxSendAttempts)
+	}
+	if done != 1 {
+		t.Errorf("done ran %d times, want 1", done)
+	}
+	if st := q.stats(); st.Dead != 1 {
+		t.Errorf("dead = %d, want 1", st.Dead)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBucket(1, 3)
+	for i := 0; i < 3; i++ {
+		if w := b.wait(now); w != 0 {
+			t.Fatalf("burst token %d: wait %v", i, w)
+		}
+		b.take()
+	}
+	if w := b.wait(now); w != time.Second {
+		t.Errorf("empty bucket: wait %v, want 1s", w)
+	}
+	if w := b.wait(now.Add(time.Second)); w != 0 {
+		t.Errorf("after refill: wait %v, want 0", w)
+	}
+	b.until = now.Add(time.Minute) // 429 pause
+	if w := b.wait(now.Add(2 * time.Second)); w != 58*time.Second {
+		t.Errorf("paused bucket: wait %v, want 58s", w)
+	}
+}
+
+// popAll pops every message that may be sent at now, as "chat:text".
+func popAll(q *outQueue, now time.Time) []string {
+	var out []string
+	for {
+		m, _ := q.next(now)
+		if m == nil {
+			return out
+		}
+		out = append(out, fmt.Sprintf("%d:%s", m.chatID(), m.params.Text))
+	}
+}
+
+func TestQueueRoundRobin(t *testing.T) {
+	q := newOutQueue(nil)
+	for _, p := range []struct {
+		chat int64
+		text string
+	}{{1, "a1"}, {1, "a2"}, {2, "b1"}, {3, "c1"}, {1, "a3"}} {
+		q.push(&outMsg{params: htmlParams(p.chat, p.text)})
+	}
+	got := popAll(q, time.Now())
+	want := []string{"1:a1", "2:b1", "3:c1", "1:a2", "1:a3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+}
+
+func TestQueueChatRateLimits(t *testing.T) {
+	q := newOutQueue(nil)
+	for i := 0; i < chatBurst+1; i++ {
+		q.push(&outMsg{params: htmlParams(1, "dm")})
+		q.push(&outMsg{params: htmlParams(-100, "group")})
+	}
+	now := time.Now()
+	if got := len(popAll(q, now)); got != 2*chatBurst {
+		t.Fatalf("sent %d at once, want %d (the burst of each chat)", got, 2*chatBurst)
+	}
+	// The private chat refills at chatRate, the group at groupRate.
+	_, wait := q.next(now)
+	if wait != time.Second {
+		t.Errorf("wait %v, want 1s for the private chat", wait)
+	}
+	if got := popAll(q, now.Add(time.Second)); !reflect.DeepEqual(got, []string{"1:dm"}) {
+		t.Errorf("after 1s sent %v, want only the private chat", got)
+	}
+	if got := popAll(q, now.Add(3*time.Second)); !reflect.DeepEqual(got, []string{"-100:group"}) {
+		t.Errorf("after 3s sent %v, want the group", got)
+	}
+}
+
+func TestQueueNotBeforeDoesNotBlockOtherChats(t *testing.T) {
+	q := newOutQueue(nil)
+	now := time.Now()
+	q.push(&outMsg{params: htmlParams(1, "retry"), notBefore: now.Add(10 * time.Second)})
+	q.push(&outMsg{params: htmlParams(2, "fresh")})
+	if got := popAll(q, now); !reflect.DeepEqual(got, []string{"2:fresh"}) {
+		t.Errorf("sent %v, want only the other chat", got)
+	}
+	if _, wait := q.next(now); wait != 10*time.Second {
+		t.Errorf("wait %v, want 10s", wait)
+	}
+}
+
+func TestQueueFullDropsOldestOfChat(t *testing.T) {
+	q := newOutQueue(nil)
+	finished := 0
+	for i := 0; i < maxQueued+1; i++ {
+		q.push(&outMsg{params: htmlParams(1, strconv.Itoa(i)), done: func() { finished++ }})
+	}
+	st := q.stats()
+	if st.Pending != maxQueued || st.Dead != 1 || finished != 1 {
+		t.Fatalf("stats %+v with %d finished, want %d pending, 1 dead and finished", st, finished, maxQueued)
+	}
+	if m, _ := q.next(time.Now()); m == nil || m.params.Text != "1" {
+		t.Errorf("head is %v, want message 1 (0 was dropped)", m)
+	}
+	if d := q.deadLetters(1, 0); len(d) != 1 || d[0].Err != "queue full" {
+		t.Errorf("dead letters %+v", d)
+	}
+}
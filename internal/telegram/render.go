@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xsamyy/solwatch/internal/tracker"
+	"github.com/0xsamyy/solwatch/internal/txdecode"
+)
+
+// lamportsPerSOL is the number of lamports in one SOL.
+const lamportsPerSOL = 1_000_000_000
+
+// maxAlertLogLines caps how many program log lines are copied into an alert.
+const maxAlertLogLines = 6
+
+// renderEvent turns a tracker Event into one Telegram HTML message.
+// name is how the wallet is shown, e.g. "Alpha Whale (ABCD...WXYZ)".
+func renderEvent(e tracker.Event, name string) string {
+	var b strings.Builder
+	wallet := walletLink(e.Wallet, name)
+
+	switch e.Kind {
+	case tracker.KindAccount:
+		// 🚨 Activity: ABCD...WXYZ +1.25 SOL (balance 10.4 SOL) at slot N · tx 5abc...wxyz
+		if e.Account == nil {
+			b.WriteString("🚨 <b>Activity Detected:</b> " + wallet)
+			break
+		}
+		a := e.Account
+		bal := formatSOL(a.Lamports) + " SOL"
+		switch {
+		case !a.HavePrev:
+			fmt.Fprintf(&b, "🚨 <b>Activity:</b> %s (balance %s) at slot %d", wallet, bal, e.Slot)
+		case a.PrevLamports == a.Lamports:
+			fmt.Fprintf(&b, "🚨 <b>Activity:</b> %s (balance unchanged, %s) at slot %d", wallet, bal, e.Slot)
+		default:
+			fmt.Fprintf(&b, "🚨 <b>Activity:</b> %s <b>%s</b> (balance %s) at slot %d",
+				wallet, formatDeltaSOL(a.PrevLamports, a.Lamports), bal, e.Slot)
+		}
+		if e.Signature != "" {
+			fmt.Fprintf(&b, " · tx %s", txLink(e.Signature))
+		}
+
+	case tracker.KindTx:
+		// 🚨 Tx: ABCD...WXYZ ✅ 5sig...abcd at slot N, then the first log lines
+		fmt.Fprintf(&b, "🚨 <b>Tx:</b> %s %s %s at slot %d", wallet, txStatus(e.Tx), txLink(e.Signature), e.Slot)
+		if e.Tx != nil && len(e.Tx.Logs) > 0 {
+			lines := e.Tx.Logs
+			if len(lines) > maxAlertLogLines {
+				lines = lines[:maxAlertLogLines]
+			}
+			b.WriteString("\n<pre>")
+			for i, l := range lines {
+				if i > 0 {
+					b.WriteByte('\n')
+				}
+				b.WriteString(escapeHTML(l))
+			}
+			if more := len(e.Tx.Logs) - len(lines); more > 0 {
+				fmt.Fprintf(&b, "\n…(+%d more)", more)
+			}
+			b.WriteString("</pre>")
+		}
+
+	case tracker.KindToken:
+		// 🪙 Token: ABCD...WXYZ +1.2M BONK (balance 3.4M) at slot N
+		t := e.Token
+		if t == nil {
+			b.WriteString("🪙 <b>Token activity:</b> " + wallet)
+			break
+		}
+		cur, _ := new(big.Int).SetString(t.Amount, 10)
+		if cur == nil {
+			cur = new(big.Int)
+		}
+		mint := fmt.Sprintf(`<a href="https://solscan.io/token/%s">%s</a>`, t.Mint, escapeHTML(txdecode.Symbol(t.Mint)))
+		bal := txdecode.FormatAmount(cur, t.Decimals)
+		prev, ok := new(big.Int).SetString(t.PrevAmount, 10)
+		if !ok {
+			fmt.Fprintf(&b, "🪙 <b>Token:</b> %s %s changed (balance %s) at slot %d", wallet, mint, bal, e.Slot)
+			break
+		}
+		delta := new(big.Int).Sub(cur, prev)
+		sign := "+"
+		if delta.Sign() < 0 {
+			sign = "-"
+		}
+		fmt.Fprintf(&b, "🪙 <b>Token:</b> %s <b>%s%s</b> %s (balance %s) at slot %d",
+			wallet, sign, txdecode.FormatAmount(delta, t.Decimals), mint, bal, e.Slot)
+
+	case tracker.KindRecovered:
+		// ♻️ Recovered: ABCD...WXYZ ✅ 5sig...abcd at slot N
+		fmt.Fprintf(&b, "♻️ <b>Recovered:</b> %s %s %s at slot %d", wallet, txStatus(e.Tx), txLink(e.Signature), e.Slot)
+
+	case tracker.KindNotice:
+		b.WriteString("⚠️ ")
+		if e.Wallet != "" {
+			b.WriteString(wallet + ": ")
+		}
+		b.WriteString(escapeHTML(e.Message))
+
+	case tracker.KindError:
+		b.WriteString("⛔ ")
+		if e.Wallet != "" {
+			b.WriteString(wallet + ": ")
+		}
+		b.WriteString(escapeHTML(e.Message))
+
+	case tracker.KindResolved:
+		b.WriteString("✅ ")
+		if e.Wallet != "" {
+			b.WriteString(wallet + ": ")
+		}
+		b.WriteString(escapeHTML(e.Message))
+
+	default:
+		fmt.Fprintf(&b, "🚨 <b>%s:</b> %s", escapeHTML(string(e.Kind)), wallet)
+	}
+
+	if e.Summary != nil {
+		b.WriteString("\n💱 " + escapeHTML(e.Summary.Text))
+	}
+	return b.String()
+}
+
+func txStatus(t *tracker.TxStatus) string {
+	if t == nil || !t.Failed {
+		return "✅"
+	}
+	return "❌ failed <code>" + escapeHTML(string(t.Err)) + "</code>"
+}
+
+// walletLink links a wallet's Solscan page; name defaults to the short address.
+func walletLink(addr, name string) string {
+	if name == "" {
+		name = shortAddr(addr)
+	}
+	return fmt.Sprintf(`<a href="https://solscan.io/account/%s">%s</a>`, addr, escapeHTML(name))
+}
+
+func txLink(sig string) string {
+	return fmt.Sprintf(`<a href="https://solscan.io/tx/%s">%s</a>`, sig, shortAddr(sig))
+}
+
+// shortAddr renders long base58 strings as ABCD...WXYZ.
+func shortAddr(s string) string {
+	if len(s) <= 8 {
+		return s
+	}
+	return s[:4] + "..." + s[len(s)-4:]
+}
+
+// formatSOL renders lamports as SOL without trailing zeros (e.g. 10.4).
+func formatSOL(lamports uint64) string {
+	whole := lamports / lamportsPerSOL
+	frac := lamports % lamportsPerSOL
+	if frac == 0 {
+		return fmt.Sprintf("%d", whole)
+	}
+	return strings.TrimRight(fmt.Sprintf("%d.%09d", whole, frac), "0")
+}
+
+// formatDeltaSOL renders a signed balance change, e.g. "+1.25 SOL".
+func formatDeltaSOL(prev, cur uint64) string {
+	if cur >= prev {
+		return "+" + formatSOL(cur-prev) + " SOL"
+	}
+	return "-" + formatSOL(prev-cur) + " SOL"
+}
@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+// handleStatus shows the subscription counters of one wallet: /status <addr>.
+func (h *Handler) handleStatus(ctx context.Context, chatID int64, args []string) {
+	if len(args) != 1 {
+		h.sendHTML(ctx, chatID, "usage: <code>/status &lt;address&gt;</code>")
+		return
+	}
+	addr := args[0]
+	if !h.requireWatched(ctx, chatID, addr) {
+		return
+	}
+	st, ok := h.tm.SubscriberStats(addr)
+	if !ok {
+		h.sendHTML(ctx, chatID, "<code>"+escapeHTML(addr)+"</code> has no subscriber")
+		return
+	}
+
+	state := "🟢 subscribed"
+	switch {
+	case st.Failed:
+		state = "⛔ failed"
+	case !st.Connected:
+		state = "🔴 disconnected"
+	case !st.Open:
+		state = "🟠 connected, awaiting ack"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>📡 %s</b>\n", walletLink(addr, h.walletName(ctx, addr)))
+	fmt.Fprintf(&b, "• State: %s (%s)\n", state, st.Mode)
+	fmt.Fprintf(&b, "• Connects: <code>%d</code> · Disconnects: <code>%d</code>\n", st.Connects, st.Disconnects)
+	fmt.Fprintf(&b, "• Last notification: %s\n", ago(st.LastNotification))
+	if st.Backoff > 0 {
+		fmt.Fprintf(&b, "• Reconnect backoff: <code>%s</code>\n", st.Backoff.Round(time.Millisecond))
+	}
+	if st.LastError != "" {
+		fmt.Fprintf(&b, "• Last error: <code>%s</code> (%s)\n", escapeHTML(st.LastError), ago(st.LastErrorAt))
+	}
+	h.sendHTML(ctx, chatID, strings.TrimSuffix(b.String(), "\n"))
+}
+
+// handleHealth sends the /health report. The admin chat gets all of it;
+// other chats the part about the wallets they watch (see health.Report.Scoped).
+func (h *Handler) handleHealth(ctx context.Context, chatID int64) {
+	h.sendHTML(ctx, chatID, h.healthReport(ctx, chatID))
+}
+
+// healthReport renders /health for chatID.
+func (h *Handler) healthReport(ctx context.Context, chatID int64) string {
+	rep := h.hlth.Snapshot(ctx)
+	dead := int64(0) // every chat
+	if chatID != h.adminID {
+		// Other chats only see the wallets they watch.
+		watched, err := h.st.WatchedBy(ctx, chatID)
+		if err != nil {
+			logger.Warn("health: watched wallets", "chat", chatID, "error", err)
+		}
+		rep = rep.Scoped(watched)
+		dead = chatID
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		"<b>📊 Health Report</b>\n"+
+			"• Tracked (memory): <code>%d</code>\n"+
+			"• Connected: <code>%d</code>\n"+
+			"• Subscribed: <code>%d</code>\n"+
+			"• Dropped: <code>%d</code>\n"+
+			"• Failed: <code>%d</code>\n"+
+			"• Connections: <code>%d</code>\n"+
+			"• Tracked (store): <code>%d</code>\n"+
+			"• Time: <code>%s</code>",
+		rep.Tracked, rep.Connected, rep.Open, len(rep.Dropped), len(rep.Failed), rep.Conns, rep.TrackedPersisted, rep.GeneratedAt.Format(time.RFC3339),
+	)
+	if len(rep.Dropped) > 0 {
+		b.WriteString("\n\n<b>📉 Dropped</b>")
+		for i, a := range rep.Dropped {
+			if i == maxHealthList {
+				fmt.Fprintf(&b, "\n…and %d more", len(rep.Dropped)-i)
+				break
+			}
+			b.WriteString("\n• " + walletLink(a, h.walletName(ctx, a)))
+		}
+	}
+	if len(rep.Flapping) > 0 {
+		b.WriteString("\n\n<b>🔁 Top flapping</b>")
+		for _, st := range rep.Flapping {
+			b.WriteString(h.flappingLine(ctx, st))
+		}
+	}
+	if len(rep.Failed) > 0 {
+		b.WriteString("\n\n<b>⛔ Failed</b>")
+		for _, f := range rep.Failed {
+			fmt.Fprintf(&b, "\n• %s %s", walletLink(f.Addr, h.walletName(ctx, f.Addr)), escapeHTML(f.Reason))
+		}
+	}
+	if len(rep.Connections) > 0 {
+		b.WriteString("\n\n<b>🔌 Connections</b>")
+		for _, c := range rep.Connections {
+			state := "🟢"
+			if !c.Connected {
+				state = "🔴"
+			}
+			fmt.Fprintf(&b, "\n%s #%d <code>%s</code> subs=%d", state, c.ID, escapeHTML(c.Endpoint), c.Subscriptions)
+		}
+	}
+	if len(rep.Endpoints) > 1 {
+		b.WriteString("\n\n<b>🌐 Endpoints</b>")
+		for _, e := range rep.Endpoints {
+			state := "🟢"
+			if !e.Healthy {
+				state = "🟠"
+			}
+			fmt.Fprintf(&b, "\n%s <code>%s</code> prio=%d score=%.0f fails=%d rtt=%s lag=%d",
+				state, escapeHTML(e.Name), e.Priority, e.Score, e.DialFailures, e.Latency.Round(time.Millisecond), e.SlotLag)
+		}
+	}
+	fmt.Fprintf(&b, "\n\n<b>📈 Since start</b>\nnotifications=%d alerts=%d send_failures=%d dials=%d dial_errors=%d reconnects=%d",
+		rep.Notifications, rep.AlertsSent, rep.SendFailures, rep.Dials, rep.DialErrors, rep.Reconnects)
+	qs := h.out.stats()
+	fmt.Fprintf(&b, "\n\n<b>📤 Outbound</b>\nqueued=%d sent=%d retried=%d dead=%d", qs.Pending, qs.Sent, qs.Retried, qs.Dead)
+	for _, d := range h.out.deadLetters(maxDeadList, dead) {
+		fmt.Fprintf(&b, "\n• <code>%s</code> chat <code>%d</code>: %s", d.At.Format("01-02 15:04:05"), d.ChatID, escapeHTML(d.Err))
+	}
+	return b.String()
+}
+
+// flappingLine renders one /health "top flapping" entry.
+func (h *Handler) flappingLine(ctx context.Context, st tracker.SubscriberStats) string {
+	line := fmt.Sprintf("\n• %s disconnects=%d", walletLink(st.Addr, h.walletName(ctx, st.Addr)), st.Disconnects)
+	if st.LastError != "" {
+		line += " last error: " + escapeHTML(st.LastError)
+	}
+	return line
+}
+
+// ago renders t as "3m12s ago" (or "never").
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return time.Since(t).Round(time.Second).String() + " ago"
+}
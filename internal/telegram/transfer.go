@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/0xsamyy/solwatch/internal/store"
+)
+
+// maxImportSize caps the size of an uploaded wallet file.
+const maxImportSize = 1 << 20
+
+// maxReportRows caps how many invalid rows are listed in an import report.
+const maxReportRows = 20
+
+// handleExport sends the wallet set as a document: /export [json|csv].
+func (h *Handler) handleExport(ctx context.Context, chatID int64, args []string) {
+	f := store.FormatJSON
+	if len(args) > 0 {
+		var err error
+		if f, err = store.ParseFormat(args[0]); err != nil || len(args) > 1 {
+			h.sendHTML(ctx, chatID, "usage: <code>/export [json|csv]</code>")
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := h.st.ExportWallets(ctx, &buf, f, chatID); err != nil {
+		h.sendHTML(ctx, chatID, fmt.Sprintf("export failed: <code>%v</code>", err))
+		return
+	}
+	name := fmt.Sprintf("solwatch-wallets-%s.%s", time.Now().UTC().Format("20060102-150405"), f)
+	_, err := h.bot.SendDocument(ctx, &tg.SendDocumentParams{
+		ChatID:   chatID,
+		Document: &models.InputFileUpload{Filename: name, Data: &buf},
+	})
+	if err != nil {
+		logger.Warn("send document failed", "chat", chatID, "error", err)
+		h.sendHTML(ctx, chatID, fmt.Sprintf("export failed: <code>%v</code>", err))
+	}
+}
+
+// handleImport applies a wallet file the command replies to:
+// /import [merge|replace]. The tracker is synced to the new set afterwards.
+func (h *Handler) handleImport(ctx context.Context, m *models.Message, args []string) {
+	mode := store.ImportMerge
+	if len(args) > 0 {
+		var err error
+		if mode, err = store.ParseImportMode(args[0]); err != nil || len(args) > 1 {
+			h.sendHTML(ctx, m.Chat.ID, "usage: reply to a .json/.csv file with <code>/import [merge|replace]</code>")
+			return
+		}
+	}
+	if m.ReplyToMessage == nil || m.ReplyToMessage.Document == nil {
+		h.sendHTML(ctx, m.Chat.ID, "usage: reply to a .json/.csv file with <code>/import [merge|replace]</code>")
+		return
+	}
+	doc := m.ReplyToMessage.Document
+	if doc.FileSize > maxImportSize {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("import failed: file larger than %d KiB", maxImportSize>>10))
+		return
+	}
+
+	data, err := h.download(ctx, doc.FileID)
+	if err != nil {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("import failed: <code>%v</code>", err))
+		return
+	}
+	f := store.DetectFormat(data)
+	if strings.HasSuffix(strings.ToLower(doc.FileName), ".csv") {
+		f = store.FormatCSV
+	}
+
+	rep, err := h.st.ImportWallets(ctx, bytes.NewReader(data), f, mode, m.Chat.ID)
+	if err != nil {
+		h.sendHTML(ctx, m.Chat.ID, fmt.Sprintf("import failed: <code>%v</code>", err))
+		return
+	}
+	if rep.Applied {
+		h.syncImported(ctx, m.Chat.ID, rep)
+	}
+	h.sendHTML(ctx, m.Chat.ID, renderImportReport(rep, mode))
+}
+
+// syncImported makes the tracker match an applied import for chatID.
+func (h *Handler) syncImported(ctx context.Context, chatID int64, rep store.ImportReport) {
+	for _, a := range rep.Removed {
+		_ = h.tm.Untrack(ctx, chatID, a)
+	}
+	for _, row := range rep.Rows {
+		if row.Status == store.RowInvalid {
+			continue
+		}
+		if err := h.tm.Track(ctx, chatID, row.Address, h.storedMode(ctx, row.Address)); err != nil {
+			logger.Warn("import track", "wallet", row.Address, "error", err)
+		}
+	}
+}
+
+// download fetches a Telegram file by id, up to maxImportSize bytes.
+func (h *Handler) download(ctx context.Context, fileID string) ([]byte, error) {
+	file, err := h.bot.GetFile(ctx, &tg.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.bot.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err) // the URL embeds the bot token; don't echo it
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: http %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	if len(data) > maxImportSize {
+		return nil, fmt.Errorf("file larger than %d KiB", maxImportSize>>10)
+	}
+	return data, nil
+}
+
+func renderImportReport(rep store.ImportReport, mode store.ImportMode) string {
+	var b strings.Builder
+	invalid := rep.Count(store.RowInvalid)
+	if rep.Applied {
+		fmt.Fprintf(&b, "<b>📥 Import (%s):</b> added %d, updated %d, invalid %d",
+			mode, rep.Count(store.RowAdded), rep.Count(store.RowUpdated), invalid)
+		if mode == store.ImportReplace {
+			fmt.Fprintf(&b, ", removed %d", len(rep.Removed))
+		}
+	} else {
+		fmt.Fprintf(&b, "<b>📥 Import (%s) not applied:</b> %d invalid row(s); fix them or use merge", mode, invalid)
+	}
+
+	shown := 0
+	for _, row := range rep.Rows {
+		if row.Status != store.RowInvalid {
+			continue
+		}
+		if shown == maxReportRows {
+			fmt.Fprintf(&b, "\n…and %d more", invalid-shown)
+			break
+		}
+		fmt.Fprintf(&b, "\n• line %d <code>%s</code>: %s", row.Line, escapeHTML(shortAddr(row.Address)), escapeHTML(row.Err))
+		shown++
+	}
+	return b.String()
+}
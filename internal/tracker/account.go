@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AccountNotification is the typed form of an accountNotification push
+// (jsonParsed encoding).
+type AccountNotification struct {
+	Slot       uint64          `json:"slot"`
+	Lamports   uint64          `json:"lamports"`
+	Owner      string          `json:"owner"`
+	Executable bool            `json:"executable"`
+	RentEpoch  uint64          `json:"rentEpoch"`
+	Space      uint64          `json:"space"`
+	Data       json.RawMessage `json:"data"` // parsed object, or [payload, encoding]
+}
+
+// decodeAccountNotification decodes params.result of an accountNotification:
+//
+//	{"context":{"slot":N},"value":{"lamports":..,"owner":..,"data":..,...}}
+func decodeAccountNotification(raw json.RawMessage) (AccountNotification, error) {
+	var env struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value *struct {
+			Lamports   uint64          `json:"lamports"`
+			Owner      string          `json:"owner"`
+			Executable bool            `json:"executable"`
+			RentEpoch  uint64          `json:"rentEpoch"`
+			Space      uint64          `json:"space"`
+			Data       json.RawMessage `json:"data"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return AccountNotification{}, fmt.Errorf("decode account notification: %w", err)
+	}
+	if env.Value == nil {
+		// Account closed (or provider sent an empty value).
+		return AccountNotification{}, errors.New("account notification without value")
+	}
+	return AccountNotification{
+		Slot:       env.Context.Slot,
+		Lamports:   env.Value.Lamports,
+		Owner:      env.Value.Owner,
+		Executable: env.Value.Executable,
+		RentEpoch:  env.Value.RentEpoch,
+		Space:      env.Value.Space,
+		Data:       env.Value.Data,
+	}, nil
+}
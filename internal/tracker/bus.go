@@ -0,0 +1,134 @@
+package tracker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy decides what Publish does when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// DropNewest discards the event being published.
+	DropNewest Policy = iota
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+	// Block applies backpressure: Publish waits up to blockTimeout for room,
+	// then drops the event. With an outbox the event stays pending there, so
+	// the sink can still redeliver it (see BusSubscription.Dropped).
+	Block
+)
+
+// blockTimeout bounds how long a Block subscriber can stall a publisher.
+// A variable so tests can shorten it.
+var blockTimeout = 5 * time.Second
+
+// Bus fans published Events out to every attached subscriber. Each
+// subscriber has its own bounded buffer, so a slow sink cannot grow memory
+// without limit; what happens on overflow is its Policy.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*BusSubscription]struct{}
+	outbox Outbox // optional; see UseOutbox
+}
+
+// NewBus returns an empty Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*BusSubscription]struct{})}
+}
+
+// BusSubscription is one consumer attached to a Bus.
+type BusSubscription struct {
+	Name string
+
+	bus     *Bus
+	ch      chan Event
+	policy  Policy
+	mu      sync.Mutex // serializes DropOldest's pop+push
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Subscribe attaches a consumer with a buffer of size buffer (min 1).
+func (b *Bus) Subscribe(name string, buffer int, policy Policy) *BusSubscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &BusSubscription{Name: name, bus: b, ch: make(chan Event, buffer), policy: policy}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// C is the channel events are delivered on. It is closed by Close.
+func (s *BusSubscription) C() <-chan Event { return s.ch }
+
+// Dropped is how many events this subscriber lost to overflow.
+func (s *BusSubscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Close detaches the subscriber and closes its channel.
+func (s *BusSubscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+
+		s.mu.Lock()
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// Publish delivers e to every subscriber according to its Policy, after
+// persisting it in the outbox (if any).
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if !b.persist(e) {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		s.deliver(e)
+	}
+}
+
+// deliver is called with the bus read lock held, so Close cannot run
+// concurrently and the channel is still open.
+func (s *BusSubscription) deliver(e Event) {
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- e:
+		case <-time.After(blockTimeout):
+			s.dropped.Add(1)
+		}
+
+	case DropOldest:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for {
+			select {
+			case s.ch <- e:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+
+	default: // DropNewest
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
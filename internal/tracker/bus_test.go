@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func ev(slot uint64) Event {
+	return Event{Wallet: "W", Kind: KindAccount, Slot: slot, Account: &AccountChange{Lamports: slot}}
+}
+
+// drain returns the slots buffered in s without blocking.
+func drain(s *BusSubscription) []uint64 {
+	var out []uint64
+	for {
+		select {
+		case e := <-s.C():
+			out = append(out, e.Slot)
+		default:
+			return out
+		}
+	}
+}
+
+func TestBusDropNewest(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe("t", 2, DropNewest)
+	for i := uint64(1); i <= 4; i++ {
+		b.Publish(ev(i))
+	}
+	if got := drain(s); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("buffered %v, want [1 2]", got)
+	}
+	if s.Dropped() != 2 {
+		t.Errorf("dropped = %d, want 2", s.Dropped())
+	}
+}
+
+func TestBusDropOldest(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe("t", 2, DropOldest)
+	for i := uint64(1); i <= 4; i++ {
+		b.Publish(ev(i))
+	}
+	if got := drain(s); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("buffered %v, want [3 4]", got)
+	}
+	if s.Dropped() != 2 {
+		t.Errorf("dropped = %d, want 2", s.Dropped())
+	}
+}
+
+func TestBusBlockWaitsForRoom(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe("t", 1, Block)
+	b.Publish(ev(1))
+
+	published := make(chan struct{})
+	go func() {
+		b.Publish(ev(2))
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish returned while the buffer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if e := <-s.C(); e.Slot != 1 {
+		t.Fatalf("got slot %d, want 1", e.Slot)
+	}
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish did not resume once there was room")
+	}
+	if e := <-s.C(); e.Slot != 2 || s.Dropped() != 0 {
+		t.Errorf("got slot %d with %d dropped, want 2 and none", e.Slot, s.Dropped())
+	}
+}
+
+func TestBusBlockDropsAfterTimeout(t *testing.T) {
+	defer func(d time.Duration) { blockTimeout = d }(blockTimeout)
+	blockTimeout = 20 * time.Millisecond
+
+	b := NewBus()
+	s := b.Subscribe("t", 1, Block)
+	b.Publish(ev(1))
+	b.Publish(ev(2)) // nobody reads: waits blockTimeout, then drops
+	if got := drain(s); len(got) != 1 || got[0] != 1 || s.Dropped() != 1 {
+		t.Errorf("got %v with %d dropped, want [1] and 1", got, s.Dropped())
+	}
+}
+
+func TestBusCloseDetaches(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe("t", 1, DropNewest)
+	s.Close()
+	s.Close()        // idempotent
+	b.Publish(ev(1)) // must not panic on the closed channel
+	if _, ok := <-s.C(); ok {
+		t.Error("channel still open after Close")
+	}
+}
@@ -0,0 +1,84 @@
+package tracker
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// maxLookups caps concurrent HTTP RPC lookups (signature resolution,
+	// getTransaction) across all subscribers of a Manager.
+	maxLookups = 8
+	// maxLookupBacklog is how many events of one wallet may wait for
+	// lookups. Beyond it events are published without enrichment (still in
+	// order), so a busy wallet cannot pile up RPC work.
+	maxLookupBacklog = 16
+	// maxBacklog caps one wallet's queued events; more are dropped (a
+	// sink is stuck far beyond its own buffer and block timeout).
+	maxBacklog = 1024
+)
+
+// limiter is a counting semaphore; a nil limiter never blocks.
+type limiter chan struct{}
+
+// acquire takes a slot, reporting false if ctx ends first.
+func (l limiter) acquire(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l limiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// dispatcher runs one subscriber's jobs one at a time, in arrival order, on
+// a goroutine that only exists while there is work.
+type dispatcher struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+// do queues job, reporting false if the backlog is full. enrich tells the
+// job whether the backlog still allows RPC lookups.
+func (d *dispatcher) do(job func(enrich bool)) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.queue) >= maxBacklog {
+		return false
+	}
+	enrich := len(d.queue) < maxLookupBacklog
+	d.queue = append(d.queue, func() { job(enrich) })
+	if !d.running {
+		d.running = true
+		go d.drain()
+	}
+	return true
+}
+
+func (d *dispatcher) drain() {
+	for {
+		d.mu.Lock()
+		if len(d.queue) == 0 {
+			d.running = false
+			d.mu.Unlock()
+			return
+		}
+		job := d.queue[0]
+		d.queue[0] = nil
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+
+		job()
+	}
+}
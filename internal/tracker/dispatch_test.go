@@ -0,0 +1,89 @@
+package tracker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherOrderAndBacklog(t *testing.T) {
+	var d dispatcher
+	started, release := make(chan struct{}), make(chan struct{})
+	d.do(func(bool) {
+		close(started)
+		<-release
+	})
+	<-started // the queue is empty again; the first job holds the worker
+
+	var mu sync.Mutex
+	var ran []int
+	enriched := 0
+	var wg sync.WaitGroup
+	for i := 0; i < maxBacklog; i++ {
+		wg.Add(1)
+		ok := d.do(func(enrich bool) {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, i)
+			if enrich {
+				enriched++
+			}
+		})
+		if !ok {
+			t.Fatalf("job %d refused below the backlog cap", i)
+		}
+	}
+	if d.do(func(bool) {}) {
+		t.Error("job accepted beyond maxBacklog")
+	}
+
+	close(release)
+	wg.Wait()
+	for i, v := range ran {
+		if v != i {
+			t.Fatalf("job %d ran in position %d", v, i)
+		}
+	}
+	if enriched != maxLookupBacklog {
+		t.Errorf("enriched %d jobs, want %d", enriched, maxLookupBacklog)
+	}
+
+	// The worker goroutine exits once the queue is empty.
+	deadline := time.Now().Add(time.Second)
+	for {
+		d.mu.Lock()
+		running := d.running
+		d.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("dispatcher still running with an empty queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	var none limiter
+	if !none.acquire(context.Background()) {
+		t.Error("a nil limiter must never block")
+	}
+	none.release()
+
+	l := make(limiter, 1)
+	if !l.acquire(context.Background()) {
+		t.Fatal("first acquire failed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if l.acquire(ctx) {
+		t.Error("acquired a slot beyond the limit")
+	}
+	l.release()
+	if !l.acquire(context.Background()) {
+		t.Error("slot not returned by release")
+	}
+}
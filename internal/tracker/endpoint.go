@@ -0,0 +1,288 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Endpoint is one WebSocket RPC endpoint. Lower Priority is preferred.
+type Endpoint struct {
+	URL      string
+	Priority int
+}
+
+// Thresholds used to decide whether an endpoint is degraded.
+const (
+	maxDialFailures = 3                // consecutive dial failures
+	maxLatency      = 2 * time.Second  // smoothed handshake/ping RTT
+	maxSlotLag      = 150              // slots behind the best endpoint (~1 min)
+	slotStaleAfter  = 2 * time.Minute  // slot observations older than this are ignored
+	recoverWindow   = 60 * time.Second // healthy this long before traffic returns
+	probeInterval   = 30 * time.Second // how often degraded endpoints are re-dialed
+	failoverCheck   = 15 * time.Second // how often connections re-evaluate their endpoint
+	latencyAlpha    = 0.3              // EWMA weight of a new latency sample
+	dialTimeout     = 12 * time.Second // websocket handshake timeout
+)
+
+// endpointState tracks health signals for one Endpoint.
+type endpointState struct {
+	Endpoint
+	name string // host only; safe to show (no api key)
+
+	mu           sync.Mutex
+	dialFailures int           // consecutive
+	totalFails   int           // lifetime dial failures
+	latency      time.Duration // EWMA of handshake and ping RTT
+	slot         uint64        // highest slot seen in notifications
+	slotAt       time.Time
+	healthySince time.Time // zero while degraded
+	fatal        error     // set when the endpoint rejected our credentials
+}
+
+// EndpointInfo is a point-in-time view of an endpoint for /health.
+type EndpointInfo struct {
+	Name         string        `json:"name"`
+	Priority     int           `json:"priority"`
+	Healthy      bool          `json:"healthy"`
+	Score        float64       `json:"score"`
+	DialFailures int           `json:"dial_failures"`
+	TotalFails   int           `json:"total_dial_failures"`
+	Latency      time.Duration `json:"latency"`
+	SlotLag      uint64        `json:"slot_lag"`
+}
+
+// endpoints is the scored set of endpoints shared by a Pool.
+type endpoints struct {
+	list []*endpointState // sorted by priority
+}
+
+func newEndpoints(eps []Endpoint) *endpoints {
+	out := &endpoints{}
+	now := time.Now()
+	for _, e := range eps {
+		out.list = append(out.list, &endpointState{
+			Endpoint:     e,
+			name:         endpointName(e.URL),
+			healthySince: now.Add(-recoverWindow), // assume healthy at start
+		})
+	}
+	sort.SliceStable(out.list, func(i, j int) bool { return out.list[i].Priority < out.list[j].Priority })
+	return out
+}
+
+// endpointName returns the host of a URL, so it can be logged without secrets.
+func endpointName(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "endpoint"
+	}
+	return u.Host
+}
+
+// maxSlot is the highest fresh slot seen on any endpoint.
+func (es *endpoints) maxSlot() uint64 {
+	var m uint64
+	for _, e := range es.list {
+		e.mu.Lock()
+		if e.slot > m && time.Since(e.slotAt) < slotStaleAfter {
+			m = e.slot
+		}
+		e.mu.Unlock()
+	}
+	return m
+}
+
+// pick returns the preferred endpoint: the lowest-priority one that has been
+// healthy for recoverWindow, else the best-scoring one. Endpoints that
+// rejected our credentials are never picked; nil means all of them did.
+func (es *endpoints) pick() *endpointState {
+	top := es.maxSlot()
+	var best *endpointState
+	bestScore := math.Inf(1)
+	for _, e := range es.list {
+		if e.isFatal() {
+			continue
+		}
+		if e.stable(top) {
+			return e
+		}
+		if sc := e.score(top); sc < bestScore {
+			best, bestScore = e, sc
+		}
+	}
+	return best
+}
+
+// info returns a snapshot of every endpoint.
+func (es *endpoints) info() []EndpointInfo {
+	top := es.maxSlot()
+	out := make([]EndpointInfo, 0, len(es.list))
+	for _, e := range es.list {
+		e.mu.Lock()
+		fails, total, lat := e.dialFailures, e.totalFails, e.latency
+		e.mu.Unlock()
+		out = append(out, EndpointInfo{
+			Name:         e.name,
+			Priority:     e.Priority,
+			Healthy:      e.healthy(top),
+			Score:        e.score(top),
+			DialFailures: fails,
+			TotalFails:   total,
+			Latency:      lat,
+			SlotLag:      e.lag(top),
+		})
+	}
+	return out
+}
+
+// lag is how far this endpoint trails top, or 0 if unknown/stale.
+func (e *endpointState) lag(top uint64) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.slot == 0 || time.Since(e.slotAt) >= slotStaleAfter || e.slot >= top {
+		return 0
+	}
+	return top - e.slot
+}
+
+func (e *endpointState) isFatal() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fatal != nil
+}
+
+// healthy reports whether all signals are within thresholds.
+func (e *endpointState) healthy(top uint64) bool {
+	lag := e.lag(top)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fatal == nil && e.dialFailures < maxDialFailures && e.latency < maxLatency && lag <= maxSlotLag
+}
+
+// stable reports healthy for at least recoverWindow (avoids flapping back).
+func (e *endpointState) stable(top uint64) bool {
+	if !e.healthy(top) {
+		e.mu.Lock()
+		e.healthySince = time.Time{}
+		e.mu.Unlock()
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.healthySince.IsZero() {
+		e.healthySince = time.Now()
+	}
+	return time.Since(e.healthySince) >= recoverWindow
+}
+
+// score combines the signals into one number (lower is better).
+func (e *endpointState) score(top uint64) float64 {
+	lag := e.lag(top)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return float64(e.Priority)*100 +
+		float64(e.dialFailures)*50 +
+		float64(e.latency.Milliseconds())/20 +
+		float64(lag)/4
+}
+
+func (e *endpointState) dialed(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dialFailures = 0
+	e.observeLatencyLocked(rtt)
+}
+
+func (e *endpointState) dialFailed() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dialFailures++
+	e.totalFails++
+	if e.dialFailures >= maxDialFailures {
+		e.healthySince = time.Time{}
+	}
+}
+
+func (e *endpointState) observeLatency(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.observeLatencyLocked(rtt)
+}
+
+func (e *endpointState) observeLatencyLocked(rtt time.Duration) {
+	if e.latency == 0 {
+		e.latency = rtt
+		return
+	}
+	e.latency = time.Duration(latencyAlpha*float64(rtt) + (1-latencyAlpha)*float64(e.latency))
+}
+
+func (e *endpointState) observeSlot(slot uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if slot >= e.slot {
+		e.slot = slot
+		e.slotAt = time.Now()
+	}
+}
+
+// dial opens a WebSocket to the endpoint and records the outcome.
+// A 401/403 handshake marks the endpoint fatal and wraps errFatalDial.
+func (e *endpointState) dial(ctx context.Context) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		Proxy:             http.ProxyFromEnvironment,
+		HandshakeTimeout:  dialTimeout,
+		EnableCompression: true,
+	}
+	start := time.Now()
+	ws, resp, err := dialer.DialContext(ctx, e.URL, nil)
+	if err != nil {
+		e.dialFailed()
+		if resp != nil && fatalStatus(resp.StatusCode) {
+			err = fmt.Errorf("%w: http %d", errFatalDial, resp.StatusCode)
+			e.mu.Lock()
+			e.fatal = err
+			e.mu.Unlock()
+		}
+		return nil, err
+	}
+	e.dialed(time.Since(start))
+	return ws, nil
+}
+
+// probeLoop periodically re-dials degraded endpoints that carry no traffic,
+// so they can become preferred again once they recover.
+func (es *endpoints) probeLoop(ctx context.Context) {
+	if len(es.list) < 2 {
+		return
+	}
+	t := time.NewTicker(probeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		top := es.maxSlot()
+		for _, e := range es.list {
+			if e.healthy(top) || e.isFatal() {
+				continue
+			}
+			ws, err := e.dial(ctx)
+			if err != nil {
+				logger.Warn("probe failed", "endpoint", e.name, "error", err)
+				continue
+			}
+			_ = ws.Close()
+		}
+	}
+}
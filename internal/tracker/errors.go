@@ -0,0 +1,46 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RPCError is a JSON-RPC error object returned for a subscribe call.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Fatal reports whether retrying the same call cannot succeed: malformed
+// request, unknown method, invalid params or rejected credentials. Rate
+// limits and internal errors are retried.
+func (e *RPCError) Fatal() bool {
+	switch e.Code {
+	case -32600, -32601, -32602: // invalid request, method not found, invalid params
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	return strings.Contains(msg, "unauthorized") || strings.Contains(msg, "api key")
+}
+
+// errFatalDial marks a handshake rejected for good (bad API key, forbidden).
+var errFatalDial = errors.New("endpoint rejected credentials")
+
+// fatalStatus reports whether a handshake HTTP status means retries are futile.
+func fatalStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// Failure describes why a wallet's subscription stopped retrying.
+type Failure struct {
+	Addr   string    `json:"addr"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
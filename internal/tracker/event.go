@@ -0,0 +1,65 @@
+package tracker
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/txdecode"
+)
+
+// EventKind classifies an Event.
+type EventKind string
+
+const (
+	KindAccount   EventKind = "account"   // wallet account lamports/data changed
+	KindTx        EventKind = "tx"        // transaction mentioning the wallet (logs mode)
+	KindToken     EventKind = "token"     // SPL token account balance changed
+	KindRecovered EventKind = "recovered" // transaction replayed after a gap
+	KindNotice    EventKind = "notice"    // informational (e.g. backfill truncated)
+	KindError     EventKind = "error"     // operator alert (fatal subscription/endpoint error)
+	KindResolved  EventKind = "resolved"  // operator alert cleared (e.g. dropped subscriptions back)
+)
+
+// Event is what the tracker publishes on its Bus. It carries data, not
+// presentation: sinks (Telegram, history, ...) render it themselves.
+type Event struct {
+	Wallet    string          `json:"wallet,omitempty"`
+	Kind      EventKind       `json:"kind"`
+	Slot      uint64          `json:"slot,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	Raw       json.RawMessage `json:"raw,omitempty"` // params.result of the push, if any
+	Time      time.Time       `json:"time"`          // when the tracker received it
+
+	// Kind-specific details; nil when not applicable.
+	Account *AccountChange    `json:"account,omitempty"`
+	Tx      *TxStatus         `json:"tx,omitempty"`
+	Token   *TokenChange      `json:"token,omitempty"`
+	Summary *txdecode.Summary `json:"summary,omitempty"`
+
+	// Message is plain text for KindNotice / KindError / KindResolved.
+	Message string `json:"message,omitempty"`
+}
+
+// AccountChange is the balance view of a KindAccount event.
+type AccountChange struct {
+	Lamports     uint64 `json:"lamports"`
+	PrevLamports uint64 `json:"prev_lamports"`
+	HavePrev     bool   `json:"have_prev"` // false for the first update seen
+}
+
+// TxStatus is the outcome of a transaction (KindTx, KindRecovered).
+type TxStatus struct {
+	Failed bool            `json:"failed"`
+	Err    json.RawMessage `json:"err,omitempty"`
+	Logs   []string        `json:"logs,omitempty"`
+}
+
+// TokenChange is the balance view of a KindToken event. Amounts are raw
+// base units; PrevAmount is empty when the previous balance is unknown.
+type TokenChange struct {
+	TokenAccount string `json:"token_account"`
+	Mint         string `json:"mint"`
+	Amount       string `json:"amount"`
+	PrevAmount   string `json:"prev_amount,omitempty"`
+	Decimals     int    `json:"decimals"`
+}
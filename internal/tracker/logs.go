@@ -0,0 +1,81 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Mode selects which upstream subscription is used for a wallet.
+type Mode string
+
+const (
+	// ModeAccount uses accountSubscribe: fires when the wallet's own
+	// lamports or data change.
+	ModeAccount Mode = "account"
+	// ModeLogs uses logsSubscribe with a mentions filter: fires for every
+	// transaction that mentions the wallet, even if its balance is unchanged.
+	ModeLogs Mode = "logs"
+)
+
+// ParseMode maps user input to a Mode. Empty input means ModeAccount.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ModeAccount:
+		return ModeAccount, nil
+	case ModeLogs:
+		return ModeLogs, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (want account|logs)", s)
+	}
+}
+
+// LogsNotification is the typed form of a logsNotification push.
+type LogsNotification struct {
+	Slot      uint64          `json:"slot"`
+	Signature string          `json:"signature"`
+	Err       json.RawMessage `json:"err"` // null on success, TransactionError otherwise
+	Logs      []string        `json:"logs"`
+}
+
+// Failed reports whether the transaction carried an error.
+func (n LogsNotification) Failed() bool {
+	e := strings.TrimSpace(string(n.Err))
+	return e != "" && e != "null"
+}
+
+// decodeLogsNotification decodes params.result of a logsNotification:
+//
+//	{"context":{"slot":N},"value":{"signature":..,"err":..,"logs":[..]}}
+func decodeLogsNotification(raw json.RawMessage) (LogsNotification, error) {
+	var env struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value struct {
+			Signature string          `json:"signature"`
+			Err       json.RawMessage `json:"err"`
+			Logs      []string        `json:"logs"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return LogsNotification{}, fmt.Errorf("decode logs notification: %w", err)
+	}
+	if env.Value.Signature == "" {
+		return LogsNotification{}, fmt.Errorf("logs notification without signature")
+	}
+	return LogsNotification{
+		Slot:      env.Context.Slot,
+		Signature: env.Value.Signature,
+		Err:       env.Value.Err,
+		Logs:      env.Value.Logs,
+	}, nil
+}
+
+// shorten renders long base58 strings as ABCD...WXYZ.
+func shorten(s string) string {
+	if len(s) <= 8 {
+		return s
+	}
+	return s[:4] + "..." + s[len(s)-4:]
+}
@@ -4,56 +4,168 @@ import (
 	"context"
 	"sort"
 	"sync"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+	"github.com/0xsamyy/solwatch/internal/rpc"
 )
 
-// Manager owns the set of active Subscribers (one per wallet).
+var logger = logging.Component("tracker")
+
+// Manager owns the set of active Subscribers (one per wallet) and the
+// connection Pool that carries their subscriptions.
 // It is concurrency-safe via an internal RWMutex.
 type Manager struct {
-	wss        string
 	commitment string
+	pool       *Pool
+	bus        *Bus
+	rpc        *rpc.Client // optional; resolves signatures for account updates
+	tokens     bool        // also subscribe to each wallet's SPL token accounts
+	cursors    CursorStore // optional; enables gap recovery after reconnects
+	lookups    limiter     // bounds concurrent RPC lookups of all subscribers
 
-	mu   sync.RWMutex
-	subs map[string]*Subscriber // addr -> sub
+	mu     sync.RWMutex
+	subs   map[string]*Subscriber        // addr -> sub
+	owners map[string]map[int64]struct{} // addr -> owners holding a reference
 }
 
-// NewManager constructs a Manager that will spawn subscribers using the
-// provided WebSocket endpoint and commitment level.
-func NewManager(wss, commitment string) *Manager {
+// NewManager constructs a Manager that will multiplex subscribers over
+// connections to the provided WebSocket endpoints (failing over between
+// them by priority and health), at most maxPerConn subscriptions per
+// connection.
+func NewManager(eps []Endpoint, commitment string, maxPerConn int) *Manager {
+	bus := NewBus()
 	return &Manager{
-		wss:        wss,
 		commitment: commitment,
+		pool:       NewPool(eps, maxPerConn, bus),
+		bus:        bus,
+		lookups:    make(limiter, maxLookups),
 		subs:       make(map[string]*Subscriber),
+		owners:     make(map[string]map[int64]struct{}),
 	}
 }
 
-// Track ensures there is a running subscriber for addr.
-// If one already exists, this is a no-op.
-func (m *Manager) Track(ctx context.Context, addr string) error {
+// Bus is where subscribers publish their Events. Attach sinks with
+// Bus().Subscribe before the first Track so no event is missed.
+func (m *Manager) Bus() *Bus {
+	return m.bus
+}
+
+// UseRPC enables HTTP JSON-RPC lookups (e.g. resolving the transaction
+// signature behind an account update) for subscribers created afterwards.
+// Call it before the first Track.
+func (m *Manager) UseRPC(c *rpc.Client) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.rpc = c
+}
 
-	if _, exists := m.subs[addr]; exists {
-		return nil
-	}
+// UseCursors enables gap recovery: the last processed transaction of each
+// wallet is persisted, and after every (re)subscribe the transactions since
+// then are replayed as "recovered" alerts. Requires UseRPC.
+// Call it before the first Track.
+func (m *Manager) UseCursors(cs CursorStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors = cs
+}
 
-	sub := NewSubscriber(m.wss, m.commitment, addr)
-	m.subs[addr] = sub
-	go sub.Run(ctx) // long-running; will auto-reconnect until Stop or ctx cancel
+// TrackTokens enables SPL token account coverage (programSubscribe on the
+// Token and Token-2022 programs, filtered by owner) for subscribers created
+// afterwards. Call it before the first Track.
+func (m *Manager) TrackTokens(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens = on
+}
+
+// Track takes a reference on addr for owner (e.g. a chat id) and ensures
+// there is a running subscriber for it in the given mode. Owners share one
+// subscriber per address; tracking again with the same owner is a no-op.
+// If the subscriber exists in a different mode (or gave up after a fatal
+// error), it is replaced, for every owner.
+//
+// Membership is decided under the lock; the subscribe/unsubscribe calls on
+// the shared sockets happen after it is released, so a slow socket does not
+// block Stats, Untrack or other Tracks.
+func (m *Manager) Track(_ context.Context, owner int64, addr string, mode Mode) error {
+	old, sub := m.track(owner, addr, mode)
+	if old != nil {
+		m.pool.Remove(old)
+	}
+	if sub != nil {
+		m.pool.Add(sub) // shared connection; auto-reconnects until removed or StopAll
+	}
 	return nil
 }
 
-// Untrack stops and removes the subscriber for addr, if present.
-func (m *Manager) Untrack(_ context.Context, addr string) error {
+// track updates the membership maps for Track and returns the subscriber
+// to remove from the pool and the one to add (either may be nil).
+func (m *Manager) track(owner int64, addr string, mode Mode) (old, sub *Subscriber) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if sub, ok := m.subs[addr]; ok {
-		sub.Stop()        // graceful: closes WS and halts reconnect attempts
+	if mode == "" {
+		mode = ModeAccount
+	}
+	if m.owners[addr] == nil {
+		m.owners[addr] = make(map[int64]struct{})
+	}
+	m.owners[addr][owner] = struct{}{}
+
+	if cur, exists := m.subs[addr]; exists {
+		if cur.Mode() == mode && !cur.Failed() {
+			return nil, nil
+		}
+		// Different mode, or a failed subscriber being retried by hand.
+		cur.Stop()
+		old = cur
 		delete(m.subs, addr)
 	}
+
+	sub = NewSubscriber(m.commitment, addr, mode)
+	sub.bus = m.bus
+	sub.rpc = m.rpc
+	sub.lookups = m.lookups
+	sub.tokens = m.tokens
+	sub.cursors = m.cursors
+	if sub.tokens && sub.rpc != nil {
+		go sub.seedTokenBalances()
+	}
+	m.subs[addr] = sub
+	return old, sub
+}
+
+// Untrack drops owner's reference on addr. The subscriber is stopped and
+// removed once no owner is left.
+func (m *Manager) Untrack(_ context.Context, owner int64, addr string) error {
+	if sub := m.untrack(owner, addr); sub != nil {
+		m.pool.Remove(sub) // unsubscribes upstream; closes the connection if empty
+	}
 	return nil
 }
 
+// untrack updates the membership maps for Untrack and returns the stopped
+// subscriber to remove from the pool, if any.
+func (m *Manager) untrack(owner int64, addr string) *Subscriber {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if set, ok := m.owners[addr]; ok {
+		delete(set, owner)
+		if len(set) > 0 {
+			return nil
+		}
+		delete(m.owners, addr)
+	}
+	sub, ok := m.subs[addr]
+	if !ok {
+		return nil
+	}
+	sub.Stop()
+	delete(m.subs, addr)
+	return sub
+}
+
 // List returns a sorted snapshot of currently tracked addresses.
 func (m *Manager) List() []string {
 	m.mu.RLock()
@@ -67,38 +179,120 @@ func (m *Manager) List() []string {
 	return out
 }
 
-// Stats reports:
-//   tracked = total number of subscribers in memory
-//   open    = how many currently report IsOpen()==true
-//   dropped = addresses that ShouldBeOpen()==true but IsOpen()==false
-//
+// Owners returns the owners holding a reference on addr, sorted.
+func (m *Manager) Owners(addr string) []int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]int64, 0, len(m.owners[addr]))
+	for o := range m.owners[addr] {
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// ModeOf returns the subscription mode of addr, if tracked.
+func (m *Manager) ModeOf(addr string) (Mode, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.subs[addr]
+	if !ok {
+		return "", false
+	}
+	return s.Mode(), true
+}
+
+// ManagerStats is what Stats reports.
+type ManagerStats struct {
+	Tracked     int               // total number of subscribers in memory
+	Open        int               // how many report IsOpen()==true (subscription ACKed)
+	Dropped     []string          // ShouldBeOpen() but not IsOpen() (excluding Failed(), see Failures)
+	Subscribers []SubscriberStats // per wallet, sorted by address
+}
+
+// Stats reports subscription counts and per-subscriber counters.
 // This is used by the /health command.
-func (m *Manager) Stats() (tracked int, open int, dropped []string) {
+func (m *Manager) Stats() ManagerStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	tracked = len(m.subs)
+	st := ManagerStats{Tracked: len(m.subs), Subscribers: make([]SubscriberStats, 0, len(m.subs))}
 	for addr, s := range m.subs {
+		st.Subscribers = append(st.Subscribers, s.Stats())
 		if s.IsOpen() {
-			open++
+			st.Open++
 			continue
 		}
-		if s.ShouldBeOpen() {
-			dropped = append(dropped, addr)
+		if s.ShouldBeOpen() && !s.Failed() {
+			st.Dropped = append(st.Dropped, addr)
 		}
 	}
 	// Keep output deterministic for tests / logs.
-	sort.Strings(dropped)
-	return
+	sort.Strings(st.Dropped)
+	sort.Slice(st.Subscribers, func(i, j int) bool { return st.Subscribers[i].Addr < st.Subscribers[j].Addr })
+	return st
+}
+
+// SubscriberStats returns the counters of the subscriber for addr.
+func (m *Manager) SubscriberStats(addr string) (SubscriberStats, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.subs[addr]
+	if !ok {
+		return SubscriberStats{}, false
+	}
+	return s.Stats(), true
 }
 
-// StopAll is a helper to gracefully stop every subscriber.
-// (Not required for your commands, but useful for clean shutdowns.)
+// Connected counts subscribers whose connection socket is up, whether or
+// not their subscription has been acknowledged yet.
+func (m *Manager) Connected() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := 0
+	for _, s := range m.subs {
+		if s.IsConnected() {
+			n++
+		}
+	}
+	return n
+}
+
+// Failures lists subscribers that stopped retrying after a fatal error.
+func (m *Manager) Failures() []Failure {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []Failure
+	for _, s := range m.subs {
+		if f, ok := s.failure(); ok {
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// StopAll stops every subscriber and closes the pool's connections.
+// Call it on shutdown.
 func (m *Manager) StopAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	for addr, s := range m.subs {
-		_ = addr // for symmetry; not used
+	for _, s := range m.subs {
 		s.Stop()
 	}
+	m.pool.Close()
+}
+
+// Conns reports the shared WebSocket connections and the endpoint each uses.
+func (m *Manager) Conns() []ConnInfo {
+	return m.pool.Conns()
+}
+
+// Endpoints reports the health score of every configured endpoint.
+func (m *Manager) Endpoints() []EndpointInfo {
+	return m.pool.Endpoints()
 }
@@ -0,0 +1,81 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// outboxTimeout bounds the outbox write in Publish.
+const outboxTimeout = 5 * time.Second
+
+// Outbox persists events before they are published, so a sink can replay
+// what it had not delivered when the process stopped. The sink removes an
+// entry (by Event.Key) once it has handled it.
+type Outbox interface {
+	// PutOutbox stores data under key and reports false if key is
+	// already pending.
+	PutOutbox(ctx context.Context, key string, data []byte) (bool, error)
+}
+
+// Key is the event's idempotency key: the same on-chain activity always
+// yields the same key. Transactions are keyed by signature, balance
+// changes by slot and resulting balance (an account can change more than
+// once per slot); notices and errors are unique per publish.
+func (e Event) Key() string {
+	k := string(e.Kind) + ":" + e.Wallet
+	if e.Token != nil {
+		k += ":" + e.Token.TokenAccount
+	}
+	switch {
+	case e.Signature != "":
+		return k + ":sig:" + e.Signature
+	case e.Slot != 0 && e.Token != nil:
+		return k + ":slot:" + strconv.FormatUint(e.Slot, 10) + ":amount:" + e.Token.Amount
+	case e.Slot != 0 && e.Account != nil:
+		return k + ":slot:" + strconv.FormatUint(e.Slot, 10) + ":lamports:" + strconv.FormatUint(e.Account.Lamports, 10)
+	}
+	return k + ":t:" + strconv.FormatInt(e.Time.UnixNano(), 10)
+}
+
+// UseOutbox makes Publish persist every event in o first. An event whose
+// key is still pending is a duplicate and is not published again.
+// Call it before the first Publish.
+func (b *Bus) UseOutbox(o Outbox) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outbox = o
+}
+
+// UseOutbox is Bus().UseOutbox; call it before the first Track.
+func (m *Manager) UseOutbox(o Outbox) {
+	m.bus.UseOutbox(o)
+}
+
+// persist writes e to the outbox, reporting whether it should be
+// published. Store errors are logged and the event is published anyway:
+// live delivery matters more than durability.
+func (b *Bus) persist(e Event) bool {
+	b.mu.RLock()
+	o := b.outbox
+	b.mu.RUnlock()
+	if o == nil {
+		return true
+	}
+
+	e.Raw = nil // only useful live; keeps entries small
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Error("outbox encode", "wallet", e.Wallet, "error", err)
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), outboxTimeout)
+	defer cancel()
+	added, err := o.PutOutbox(ctx, e.Key(), data)
+	if err != nil {
+		logger.Error("outbox", "wallet", e.Wallet, "error", err)
+		return true
+	}
+	return added
+}
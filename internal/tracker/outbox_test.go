@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventKey(t *testing.T) {
+	const w = "Wallet1111111111111111111111111111111111111"
+	at := time.Unix(1760600000, 5)
+	tok := func(amount string) *TokenChange {
+		return &TokenChange{TokenAccount: "Ata1", Mint: "Mint1", Amount: amount, PrevAmount: "1"}
+	}
+	tests := []struct {
+		name string
+		e    Event
+		want string
+	}{
+		{"signature wins over slot",
+			Event{Wallet: w, Kind: KindTx, Slot: 9, Signature: "Sig1", Time: at},
+			"tx:" + w + ":sig:Sig1"},
+		{"account change",
+			Event{Wallet: w, Kind: KindAccount, Slot: 9, Account: &AccountChange{Lamports: 500, PrevLamports: 100}, Time: at},
+			"account:" + w + ":slot:9:lamports:500"},
+		{"token change",
+			Event{Wallet: w, Kind: KindToken, Slot: 9, Token: tok("7"), Time: at},
+			"token:" + w + ":Ata1:slot:9:amount:7"},
+		{"token change with signature",
+			Event{Wallet: w, Kind: KindToken, Slot: 9, Signature: "Sig2", Token: tok("7"), Time: at},
+			"token:" + w + ":Ata1:sig:Sig2"},
+		{"slot without a balance",
+			Event{Wallet: w, Kind: KindAccount, Slot: 9, Time: at},
+			"account:" + w + ":t:1760600000000000005"},
+		{"notice",
+			Event{Kind: KindNotice, Message: "hi", Time: at},
+			"notice::t:1760600000000000005"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Key(); got != tt.want {
+			t.Errorf("%s: Key() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Two balance changes of one account in one slot must not share a key,
+// or the outbox drops the second as a duplicate.
+func TestEventKeySameSlot(t *testing.T) {
+	a := Event{Wallet: "W", Kind: KindAccount, Slot: 9, Account: &AccountChange{Lamports: 500, PrevLamports: 100}}
+	b := Event{Wallet: "W", Kind: KindAccount, Slot: 9, Account: &AccountChange{Lamports: 300, PrevLamports: 500}}
+	if a.Key() == b.Key() {
+		t.Errorf("both changes keyed %q", a.Key())
+	}
+	// A replay of the same change (e.g. after a reconnect) keeps its key.
+	a.Time = a.Time.Add(time.Second)
+	a.Account = &AccountChange{Lamports: 500}
+	if got, want := a.Key(), "account:W:slot:9:lamports:500"; got != want {
+		t.Errorf("replay keyed %q, want %q", got, want)
+	}
+}
+
+// fakeOutbox reports keys it has already seen as pending.
+type fakeOutbox struct {
+	mu   sync.Mutex
+	keys map[string]bool
+	err  error
+}
+
+func (o *fakeOutbox) PutOutbox(_ context.Context, key string, _ []byte) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return false, o.err
+	}
+	if o.keys[key] {
+		return false, nil
+	}
+	o.keys[key] = true
+	return true, nil
+}
+
+func TestBusOutboxSkipsPendingKeys(t *testing.T) {
+	b := NewBus()
+	o := &fakeOutbox{keys: map[string]bool{}}
+	b.UseOutbox(o)
+	s := b.Subscribe("t", 10, DropNewest)
+
+	b.Publish(ev(1))
+	b.Publish(ev(1)) // same change again, e.g. replayed after a reconnect
+	b.Publish(ev(2))
+	if got := drain(s); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("published %v, want [1 2]", got)
+	}
+
+	// A store error must not lose live delivery.
+	o.err = errors.New("disk full")
+	b.Publish(ev(3))
+	if got := drain(s); len(got) != 1 || got[0] != 3 {
+		t.Errorf("published %v, want [3]", got)
+	}
+}
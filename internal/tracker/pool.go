@@ -0,0 +1,674 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/0xsamyy/solwatch/internal/metrics"
+	"github.com/0xsamyy/solwatch/internal/util"
+)
+
+// DefaultMaxSubsPerConn is used when the pool is created with a non-positive cap.
+const DefaultMaxSubsPerConn = 100
+
+// Pool multiplexes many wallet subscriptions over a small set of shared
+// WebSocket connections. Each connection carries at most maxPerConn upstream
+// subscriptions; new connections are dialed on demand and closed again once
+// their last subscriber leaves.
+//
+// When a socket drops, only the subscriptions that lived on that socket are
+// resubscribed (after the usual backoff + jitter).
+//
+// With several endpoints, each connection dials the preferred healthy one
+// and moves away from it when it degrades (dial failures, latency, slot
+// lag), returning once the preferred endpoint has recovered.
+type Pool struct {
+	eps        *endpoints
+	maxPerConn int
+	bus        *Bus // operator alerts (KindError) are published here
+
+	// ctx bounds every connection and the endpoint probe. It belongs to the
+	// pool, not to the Track call that happened to dial a connection, since
+	// connections are shared; Close cancels it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	conns     []*wsConn
+	owner     map[*Subscriber]*wsConn
+	nextID    int
+	probeOnce sync.Once
+}
+
+// NewPool returns an empty Pool over the given endpoints. Connections are
+// only opened once the first Subscriber is added.
+func NewPool(eps []Endpoint, maxPerConn int, bus *Bus) *Pool {
+	if maxPerConn <= 0 {
+		maxPerConn = DefaultMaxSubsPerConn
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		eps:        newEndpoints(eps),
+		maxPerConn: maxPerConn,
+		bus:        bus,
+		ctx:        ctx,
+		cancel:     cancel,
+		owner:      make(map[*Subscriber]*wsConn),
+	}
+}
+
+// Add places s on the first connection with spare capacity, dialing a new
+// connection if all existing ones are full. Connections live until they
+// are empty or the pool is closed.
+func (p *Pool) Add(s *Subscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.owner[s]; ok {
+		return
+	}
+	if !s.ShouldBeOpen() {
+		return // stopped by a concurrent Untrack/Track before it got here
+	}
+	need := len(s.streams())
+	for _, c := range p.conns {
+		if c.load()+need <= p.maxPerConn {
+			c.add(s)
+			p.owner[s] = c
+			return
+		}
+	}
+
+	p.probeOnce.Do(func() { go p.eps.probeLoop(p.ctx) })
+
+	p.nextID++
+	c := newWSConn(p.nextID, p.eps, p.bus)
+	p.conns = append(p.conns, c)
+	p.owner[s] = c
+	c.add(s)
+	go c.run(p.ctx) // long-running; reconnects until stopped or the pool is closed
+}
+
+// Remove unsubscribes s from its connection. Empty connections are closed.
+func (p *Pool) Remove(s *Subscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.owner[s]
+	if !ok {
+		return
+	}
+	delete(p.owner, s)
+	if c.remove(s) > 0 {
+		return
+	}
+	c.stop()
+	for i, cc := range p.conns {
+		if cc == c {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			break
+		}
+	}
+}
+
+// Close stops every connection in the pool and the endpoint probe.
+func (p *Pool) Close() {
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		c.stop()
+	}
+	p.conns = nil
+	p.owner = make(map[*Subscriber]*wsConn)
+}
+
+// ConnInfo is a point-in-time view of one pooled connection.
+type ConnInfo struct {
+	ID            int    `json:"id"`
+	Endpoint      string `json:"endpoint"` // host only
+	Connected     bool   `json:"connected"`
+	Subscriptions int    `json:"subscriptions"`
+}
+
+// Conns returns a snapshot of every connection owned by the pool.
+func (p *Pool) Conns() []ConnInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]ConnInfo, 0, len(p.conns))
+	for _, c := range p.conns {
+		out = append(out, c.info())
+	}
+	return out
+}
+
+// Endpoints returns the health view of every configured endpoint.
+func (p *Pool) Endpoints() []EndpointInfo {
+	return p.eps.info()
+}
+
+// stream is one upstream JSON-RPC subscription (e.g. accountSubscribe)
+// belonging to a Subscriber. A Subscriber may own several streams.
+type stream struct {
+	sub    *Subscriber
+	main   bool   // the wallet's own account/logs subscription
+	method string // e.g. "accountSubscribe"
+	unsub  string // e.g. "accountUnsubscribe"
+	params []any
+
+	// guarded by wsConn.mu
+	subID   uint64        // per session
+	acked   bool          // per session
+	session uint64        // session the last subscribe call was sent on
+	removed bool          // untracked
+	failed  bool          // rejected with a fatal error; never resubscribed
+	retry   *util.Backoff // paces resubscribes after retryable errors
+}
+
+// rpcMessage covers both call responses and subscription pushes.
+type rpcMessage struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	Method string          `json:"method"`
+	Params *struct {
+		Subscription uint64          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// wsConn is a single shared WebSocket carrying many streams.
+type wsConn struct {
+	id  int
+	eps *endpoints
+	bus *Bus
+	log *slog.Logger
+
+	switching atomic.Bool  // socket closed on purpose to move endpoints
+	pingSent  atomic.Int64 // unix nanos of the outstanding ping, 0 if none
+
+	mu      sync.Mutex
+	ep      *endpointState  // endpoint of the current/last session
+	ws      *websocket.Conn // nil while disconnected
+	subs    map[*Subscriber][]*stream
+	pending map[uint64]*stream // request id -> stream awaiting its ACK
+	active  map[uint64]*stream // subscription id -> stream
+	reqID   uint64
+	session uint64 // incremented on every successful dial
+
+	writeMu sync.Mutex // gorilla allows one concurrent writer
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newWSConn(id int, eps *endpoints, bus *Bus) *wsConn {
+	return &wsConn{
+		id:      id,
+		eps:     eps,
+		bus:     bus,
+		log:     logger.With("conn", id),
+		subs:    make(map[*Subscriber][]*stream),
+		pending: make(map[uint64]*stream),
+		active:  make(map[uint64]*stream),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+func (c *wsConn) info() ConnInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ci := ConnInfo{ID: c.id, Connected: c.ws != nil}
+	if c.ep != nil {
+		ci.Endpoint = c.ep.name
+	}
+	for _, ss := range c.subs {
+		ci.Subscriptions += len(ss)
+	}
+	return ci
+}
+
+// load is the number of upstream subscriptions carried by this connection.
+func (c *wsConn) load() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, ss := range c.subs {
+		n += len(ss)
+	}
+	return n
+}
+
+func (c *wsConn) add(s *Subscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ss := s.streams()
+	c.subs[s] = ss
+	if c.ws == nil {
+		return // will be subscribed on (re)connect
+	}
+	s.connected.Store(true)
+	for _, st := range ss {
+		c.subscribeLocked(st)
+	}
+}
+
+// remove detaches s and returns the remaining load of the connection.
+func (c *wsConn) remove(s *Subscriber) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, st := range c.subs[s] {
+		st.removed = true
+		if st.acked {
+			delete(c.active, st.subID)
+			if c.ws != nil {
+				c.callLocked(st.unsub, []any{st.subID})
+			}
+		}
+	}
+	delete(c.subs, s)
+	s.open.Store(false)
+	s.connected.Store(false)
+
+	n := 0
+	for _, ss := range c.subs {
+		n += len(ss)
+	}
+	return n
+}
+
+func (c *wsConn) stop() {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+}
+
+// run dials, subscribes every stream on the connection, reads pushes and
+// reconnects with exponential backoff + jitter until stop() or ctx cancel.
+func (c *wsConn) run(ctx context.Context) {
+	bo := util.NewBackoff(1*time.Second, 30*time.Second, 2.0, 0.2)
+	connected := false // once connected, every further dial is a reconnect
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		default:
+		}
+
+		ep := c.eps.pick()
+		if ep == nil {
+			c.fail("all endpoints rejected our credentials")
+			return
+		}
+		metrics.Dials.Inc()
+		ws, err := ep.dial(ctx)
+		if err != nil {
+			metrics.DialErrors.Inc()
+			if errors.Is(err, errFatalDial) {
+				c.log.Error("dial rejected; endpoint disabled", "endpoint", ep.name, "error", err)
+				c.alert("", fmt.Sprintf("endpoint %s disabled: handshake rejected (%v)", ep.name, err))
+				continue // try the next endpoint right away
+			}
+			wait := bo.Next()
+			c.setBackoff(wait)
+			c.log.Warn("dial failed", "endpoint", ep.name, "attempt", bo.Attempt(), "retry_in", wait, "error", err)
+			if !c.sleep(ctx, wait) {
+				return
+			}
+			continue
+		}
+		bo.Reset()
+		if connected {
+			metrics.Reconnects.Inc()
+		}
+		connected = true
+
+		// Close the socket when asked to stop while connected.
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-c.stopCh:
+				_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "stop"), time.Now().Add(2*time.Second))
+			case <-ctx.Done():
+			case <-done:
+				return
+			}
+			_ = ws.Close()
+		}()
+
+		// Keep read deadlines fresh via pong handler
+		_ = ws.SetReadDeadline(time.Now().Add(60 * time.Second))
+		ws.SetPongHandler(func(string) error {
+			if sent := c.pingSent.Swap(0); sent > 0 {
+				ep.observeLatency(time.Since(time.Unix(0, sent)))
+			}
+			return ws.SetReadDeadline(time.Now().Add(60 * time.Second))
+		})
+
+		c.attach(ws, ep)
+		go c.pingLoop(ctx, ws, done)
+		go c.failoverLoop(ctx, ws, ep, done)
+
+		readErr := c.readLoop(ws, ep)
+
+		close(done)
+		c.detach()
+		_ = ws.Close()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		default:
+		}
+
+		if c.switching.Swap(false) {
+			continue // deliberate move: reconnect right away on the better endpoint
+		}
+
+		wait := bo.Next()
+		c.setBackoff(wait)
+		c.log.Warn("read failed", "endpoint", ep.name, "attempt", bo.Attempt(), "reconnect_in", wait, "error", readErr)
+		if !c.sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+// sleep waits for d and reports false if the connection should exit instead.
+func (c *wsConn) sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.stopCh:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// attach installs a freshly dialed socket and (re)subscribes every stream.
+func (c *wsConn) attach(ws *websocket.Conn, ep *endpointState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ws = ws
+	c.ep = ep
+	c.session++
+	for s, ss := range c.subs {
+		s.connected.Store(true)
+		s.backoff.Store(0)
+		for _, st := range ss {
+			if !st.failed {
+				c.subscribeLocked(st)
+			}
+		}
+	}
+}
+
+// detach forgets all per-session state after the socket dropped.
+func (c *wsConn) detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ws = nil
+	c.pending = make(map[uint64]*stream)
+	c.active = make(map[uint64]*stream)
+	for s, ss := range c.subs {
+		for _, st := range ss {
+			st.acked = false
+			st.subID = 0
+		}
+		if s.open.Swap(false) {
+			s.disconnects.Add(1)
+		}
+		s.connected.Store(false)
+	}
+}
+
+// setBackoff records the delay before the next dial on every subscriber.
+func (c *wsConn) setBackoff(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for s := range c.subs {
+		s.backoff.Store(int64(d))
+	}
+}
+
+// fail gives up on every subscriber of the connection (e.g. no endpoint
+// accepts our credentials) and tells the admin once.
+func (c *wsConn) fail(reason string) {
+	c.mu.Lock()
+	n := len(c.subs)
+	for s := range c.subs {
+		s.markFailed(reason)
+	}
+	c.mu.Unlock()
+
+	c.log.Error("giving up", "reason", reason)
+	c.alert("", fmt.Sprintf("connection #%d stopped: %s (%d wallet(s) affected)", c.id, reason, n))
+}
+
+// alert publishes an operator-facing KindError event. It may be called on
+// the read loop, so publishing happens on its own goroutine.
+func (c *wsConn) alert(wallet, msg string) {
+	if c.bus != nil {
+		go c.bus.Publish(Event{Wallet: wallet, Kind: KindError, Time: time.Now().UTC(), Message: msg})
+	}
+}
+
+// subscribeLocked sends the subscribe call for st. Caller holds c.mu.
+func (c *wsConn) subscribeLocked(st *stream) {
+	st.acked = false
+	st.session = c.session
+	if id, ok := c.callLocked(st.method, st.params); ok {
+		c.pending[id] = st
+	}
+}
+
+// retryLater resubscribes st after its backoff, unless the session changed
+// in the meantime (a reconnect resubscribes everything anyway).
+func (c *wsConn) retryLater(st *stream) {
+	if st.retry == nil {
+		st.retry = util.NewBackoff(2*time.Second, 2*time.Minute, 2.0, 0.2)
+	}
+	wait := st.retry.Next()
+	session := c.session
+	time.AfterFunc(wait, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.ws == nil || c.session != session || st.removed || st.failed {
+			return
+		}
+		c.subscribeLocked(st)
+	})
+}
+
+// callLocked writes one JSON-RPC request and returns its id. Caller holds c.mu.
+func (c *wsConn) callLocked(method string, params []any) (uint64, bool) {
+	if c.ws == nil {
+		return 0, false
+	}
+	c.reqID++
+	id := c.reqID
+	msg := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	}
+
+	c.writeMu.Lock()
+	_ = c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	err := c.ws.WriteJSON(msg)
+	c.writeMu.Unlock()
+	if err != nil {
+		// The read loop will notice the broken socket and reconnect.
+		c.log.Warn("write failed", "method", method, "error", err)
+		return 0, false
+	}
+	return id, true
+}
+
+// pingLoop keeps the connection alive (every 20s) until done is closed.
+func (c *wsConn) pingLoop(ctx context.Context, ws *websocket.Conn, done <-chan struct{}) {
+	t := time.NewTicker(20 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			c.pingSent.CompareAndSwap(0, time.Now().UnixNano())
+			c.writeMu.Lock()
+			_ = ws.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(5*time.Second))
+			c.writeMu.Unlock()
+		}
+	}
+}
+
+// failoverLoop periodically checks whether the connection should move to a
+// better endpoint: away from a degraded one, or back to a recovered
+// preferred one. It closes the socket to trigger an immediate redial.
+func (c *wsConn) failoverLoop(ctx context.Context, ws *websocket.Conn, cur *endpointState, done <-chan struct{}) {
+	t := time.NewTicker(failoverCheck)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		best := c.eps.pick()
+		if best == nil || best == cur {
+			continue
+		}
+		if cur.healthy(c.eps.maxSlot()) && best.Priority >= cur.Priority {
+			continue
+		}
+		c.log.Info("moving endpoint", "from", cur.name, "to", best.name)
+		c.switching.Store(true)
+		_ = ws.Close()
+		return
+	}
+}
+
+// readLoop dispatches ACKs and subscription pushes until the socket fails.
+// The context slot of every push feeds the endpoint's slot-lag score.
+func (c *wsConn) readLoop(ws *websocket.Conn, ep *endpointState) error {
+	for {
+		_, raw, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+
+		// Call response: subscribe ACK (result = subscription id) or error.
+		if msg.ID != nil {
+			c.handleResponse(*msg.ID, msg)
+			continue
+		}
+
+		// Subscription push: route by subscription id.
+		if msg.Params == nil {
+			continue
+		}
+		var ctxSlot struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+		}
+		if json.Unmarshal(msg.Params.Result, &ctxSlot) == nil && ctxSlot.Context.Slot > 0 {
+			ep.observeSlot(ctxSlot.Context.Slot)
+		}
+		c.mu.Lock()
+		st := c.active[msg.Params.Subscription]
+		c.mu.Unlock()
+		if st != nil {
+			metrics.Notifications.Inc()
+			st.sub.handle(st.method, msg.Params.Result)
+		}
+	}
+}
+
+func (c *wsConn) handleResponse(id uint64, msg rpcMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.pending[id]
+	if !ok {
+		return // e.g. unsubscribe result
+	}
+	delete(c.pending, id)
+
+	if st.removed {
+		if msg.Error == nil {
+			// Untracked while the ACK was in flight; drop it upstream too.
+			var subID uint64
+			if json.Unmarshal(msg.Result, &subID) == nil {
+				c.callLocked(st.unsub, []any{subID})
+			}
+		}
+		return
+	}
+
+	if msg.Error != nil {
+		st.sub.recordError(msg.Error)
+		if msg.Error.Fatal() {
+			st.failed = true
+			st.sub.log.Error("subscribe rejected (fatal)", "method", st.method, "error", msg.Error)
+			reason := fmt.Sprintf("%s rejected: %v", st.method, msg.Error)
+			if st.main {
+				st.sub.markFailed(reason)
+			}
+			c.alert(st.sub.addr, "subscription failed: "+reason)
+			return
+		}
+		st.sub.log.Warn("subscribe rejected; will retry", "method", st.method, "error", msg.Error)
+		c.retryLater(st)
+		return
+	}
+	var subID uint64
+	if err := json.Unmarshal(msg.Result, &subID); err != nil {
+		st.sub.log.Warn("unexpected subscribe result", "method", st.method, "result", string(msg.Result))
+		return
+	}
+	st.subID = subID
+	st.acked = true
+	if st.retry != nil {
+		st.retry.Reset()
+	}
+	c.active[subID] = st
+
+	if st.main {
+		st.sub.subscribed(subID)
+		// The wallet is live again: replay anything missed since its cursor.
+		go st.sub.recoverGap()
+	}
+}
@@ -0,0 +1,197 @@
+package tracker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeSub is a subscribe call the fake node answered.
+type fakeSub struct {
+	addr string
+	id   uint64
+}
+
+// fakeNode is a WebSocket RPC node that acknowledges every subscribe with
+// a new subscription id (never reused, also not across connections) and
+// pushes account notifications on request.
+type fakeNode struct {
+	srv  *httptest.Server
+	subs chan fakeSub
+
+	mu     sync.Mutex
+	nextID uint64
+	ws     *websocket.Conn // newest connection
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	n := &fakeNode{subs: make(chan fakeSub, 16), nextID: 100}
+	var up websocket.Upgrader
+	n.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		n.mu.Lock()
+		n.ws = ws
+		n.mu.Unlock()
+		for {
+			var req struct {
+				ID     uint64            `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			if err := ws.ReadJSON(&req); err != nil {
+				return
+			}
+			var result any = true // unsubscribe
+			var sub fakeSub
+			if strings.HasSuffix(req.Method, "Subscribe") {
+				n.mu.Lock()
+				n.nextID++
+				sub.id = n.nextID
+				n.mu.Unlock()
+				_ = json.Unmarshal(req.Params[0], &sub.addr)
+				result = sub.id
+			}
+			n.write(ws, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+			if sub.id != 0 {
+				n.subs <- sub
+			}
+		}
+	}))
+	t.Cleanup(n.srv.Close)
+	return n
+}
+
+func (n *fakeNode) url() string { return "ws" + strings.TrimPrefix(n.srv.URL, "http") }
+
+func (n *fakeNode) write(ws *websocket.Conn, v any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_ = ws.WriteJSON(v)
+}
+
+// push sends an account notification for subscription id on the newest
+// connection.
+func (n *fakeNode) push(id, lamports uint64) {
+	n.mu.Lock()
+	ws := n.ws
+	n.mu.Unlock()
+	n.write(ws, map[string]any{"jsonrpc": "2.0", "method": "accountNotification", "params": map[string]any{
+		"subscription": id,
+		"result": map[string]any{
+			"context": map[string]any{"slot": lamports},
+			"value":   map[string]any{"lamports": lamports, "owner": "11111111111111111111111111111111"},
+		},
+	}})
+}
+
+// drop closes the newest connection, as a node restart would.
+func (n *fakeNode) drop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_ = n.ws.Close()
+}
+
+// subscribed waits for a subscribe call for each of addrs and returns
+// their subscription ids.
+func (n *fakeNode) subscribed(t *testing.T, addrs ...string) map[string]uint64 {
+	t.Helper()
+	ids := map[string]uint64{}
+	for len(ids) < len(addrs) {
+		select {
+		case s := <-n.subs:
+			ids[s.addr] = s.id
+		case <-time.After(5 * time.Second):
+			t.Fatalf("subscribed %v, want %v", ids, addrs)
+		}
+	}
+	return ids
+}
+
+// nextEvent waits for the next event on s.
+func nextEvent(t *testing.T, s *BusSubscription) Event {
+	t.Helper()
+	select {
+	case e := <-s.C():
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return Event{}
+	}
+}
+
+// waitOpen waits until every subscriber has its subscription acknowledged.
+func waitOpen(t *testing.T, subs ...*Subscriber) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, s := range subs {
+		for !s.IsOpen() {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s not open", s.addr)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestPoolRoutesBySubscriptionID(t *testing.T) {
+	node := newFakeNode(t)
+	bus := NewBus()
+	events := bus.Subscribe("t", 16, DropNewest)
+	p := NewPool([]Endpoint{{URL: node.url()}}, 10, bus)
+	defer p.Close()
+
+	const walletA, walletB = "WalletA", "WalletB"
+	a := NewSubscriber("confirmed", walletA, ModeAccount)
+	b := NewSubscriber("confirmed", walletB, ModeAccount)
+	a.bus, b.bus = bus, bus
+	p.Add(a)
+	p.Add(b)
+	ids := node.subscribed(t, walletA, walletB)
+	waitOpen(t, a, b)
+	if len(p.Conns()) != 1 {
+		t.Fatalf("%d connections, want both wallets on one", len(p.Conns()))
+	}
+
+	// Each push reaches the wallet its subscription id belongs to (wallets
+	// publish independently, so in either order).
+	node.push(ids[walletB], 2)
+	node.push(ids[walletA], 1)
+	got := map[string]uint64{}
+	for range 2 {
+		if e := nextEvent(t, events); e.Account != nil {
+			got[e.Wallet] = e.Account.Lamports
+		}
+	}
+	if got[walletA] != 1 || got[walletB] != 2 {
+		t.Fatalf("lamports by wallet %v, want %s=1 %s=2", got, walletA, walletB)
+	}
+
+	// After a reconnect both wallets are resubscribed under new ids; the
+	// old ones no longer route anywhere.
+	node.drop()
+	again := node.subscribed(t, walletA, walletB)
+	waitOpen(t, a, b)
+	if again[walletA] == ids[walletA] || again[walletB] == ids[walletB] {
+		t.Fatalf("ids %v reused after reconnect (before %v)", again, ids)
+	}
+	if a.SubscriptionID() != again[walletA] || b.SubscriptionID() != again[walletB] {
+		t.Errorf("subscription ids %d/%d, want %v", a.SubscriptionID(), b.SubscriptionID(), again)
+	}
+	node.push(ids[walletA], 3) // stale id: dropped
+	node.push(again[walletA], 4)
+	if e := nextEvent(t, events); e.Wallet != walletA || e.Account == nil || e.Account.Lamports != 4 {
+		t.Fatalf("event %+v, want %s with 4 lamports", e, walletA)
+	}
+	if a.disconnects.Load() != 1 || b.disconnects.Load() != 1 {
+		t.Errorf("disconnects %d/%d, want 1 each", a.disconnects.Load(), b.disconnects.Load())
+	}
+}
@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/rpc"
+)
+
+const (
+	// maxBackfill caps how many missed transactions are replayed per gap.
+	maxBackfill = 100
+	// maxBackfillDecoded caps how many of them (the newest) are fetched
+	// with getTransaction for a summary; the rest are replayed without.
+	maxBackfillDecoded = 20
+	// seenSignatures is how many recent signatures a subscriber remembers
+	// to avoid alerting twice for the same transaction.
+	seenSignatures = 256
+)
+
+// recoveryTimeout bounds one backfill run (lookups + tx decoding), so a
+// flapping wallet cannot hold the shared lookup slots for long. Summaries
+// not fetched by then are left out. A variable so tests can shorten it.
+var recoveryTimeout = time.Minute
+
+// CursorStore persists the newest processed transaction per wallet, so
+// activity missed while disconnected (or stopped) can be replayed.
+type CursorStore interface {
+	LoadCursor(ctx context.Context, addr string) (sig string, slot uint64, err error)
+	SaveCursor(ctx context.Context, addr, sig string, slot uint64) error
+}
+
+// remember records sig as alerted. It reports false if sig was already seen.
+func (s *Subscriber) remember(sig string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[sig]; ok {
+		return false
+	}
+	s.seen[sig] = struct{}{}
+	s.seenOrder = append(s.seenOrder, sig)
+	if len(s.seenOrder) > seenSignatures {
+		delete(s.seen, s.seenOrder[0])
+		s.seenOrder = s.seenOrder[1:]
+	}
+	return true
+}
+
+// markProcessed remembers sig and advances the persisted cursor.
+// It reports false if sig had already been alerted.
+func (s *Subscriber) markProcessed(sig string, slot uint64) bool {
+	if sig == "" {
+		return true
+	}
+	fresh := s.remember(sig)
+	s.saveCursor(sig, slot)
+	return fresh
+}
+
+func (s *Subscriber) saveCursor(sig string, slot uint64) {
+	if s.cursors == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.cursors.SaveCursor(ctx, s.addr, sig, slot); err != nil {
+		s.log.Error("save cursor", "error", err)
+	}
+}
+
+// recoverGap replays transactions newer than the persisted cursor. It runs
+// every time the wallet's main subscription is (re)acknowledged, so both
+// reconnects and restarts are covered. Without a cursor it only records
+// the current newest transaction as the starting point.
+func (s *Subscriber) recoverGap() {
+	if s.rpc == nil || s.cursors == nil || !s.ShouldBeOpen() {
+		return
+	}
+	if !s.recovering.CompareAndSwap(false, true) {
+		return // a backfill is already running
+	}
+	defer s.recovering.Store(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+
+	lastSig, _, err := s.cursors.LoadCursor(ctx, s.addr)
+	if err != nil {
+		s.log.Error("load cursor", "error", err)
+		return
+	}
+
+	if lastSig == "" {
+		sigs, err := s.signatures(ctx, rpc.SignaturesOpts{Limit: 1, Commitment: "confirmed"})
+		if err != nil {
+			s.log.Warn("seed cursor", "error", err)
+			return
+		}
+		if len(sigs) > 0 {
+			s.saveCursor(sigs[0].Signature, sigs[0].Slot)
+		}
+		return
+	}
+
+	sigs, err := s.signatures(ctx, rpc.SignaturesOpts{
+		Until:      lastSig,
+		Limit:      maxBackfill,
+		Commitment: "confirmed",
+	})
+	if err != nil {
+		s.log.Warn("backfill", "error", err)
+		return
+	}
+	if len(sigs) == 0 {
+		return
+	}
+	s.log.Info("backfill", "transactions", len(sigs), "since", lastSig)
+
+	// Oldest first, so alerts read in chain order.
+	for i := len(sigs) - 1; i >= 0; i-- {
+		si := sigs[i]
+		if !s.ShouldBeOpen() {
+			return
+		}
+		if !s.markProcessed(si.Signature, si.Slot) {
+			continue // already alerted live
+		}
+		e := strings.TrimSpace(string(si.Err))
+		ev := Event{
+			Wallet:    s.addr,
+			Kind:      KindRecovered,
+			Slot:      si.Slot,
+			Signature: si.Signature,
+			Time:      time.Now().UTC(),
+			Tx:        &TxStatus{Failed: e != "" && e != "null", Err: si.Err},
+		}
+		if i < maxBackfillDecoded && ctx.Err() == nil {
+			ev.Summary = s.describeTx(ctx, si.Signature)
+		}
+		s.publish(ev)
+	}
+	if len(sigs) == maxBackfill {
+		s.publish(Event{
+			Wallet:  s.addr,
+			Kind:    KindNotice,
+			Time:    time.Now().UTC(),
+			Message: fmt.Sprintf("more than %d missed transactions; only the latest %d were replayed", maxBackfill, maxBackfill),
+		})
+	}
+}
+
+// signatures is GetSignaturesForAddress for the wallet, holding a lookup
+// slot only for the call itself.
+func (s *Subscriber) signatures(ctx context.Context, opts rpc.SignaturesOpts) ([]rpc.SignatureInfo, error) {
+	if !s.lookups.acquire(ctx) {
+		return nil, ctx.Err()
+	}
+	defer s.lookups.release()
+	return s.rpc.GetSignaturesForAddress(ctx, s.addr, opts)
+}
@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/rpc"
+)
+
+// fakeCursors always returns the same cursor.
+type fakeCursors struct{ sig string }
+
+func (c fakeCursors) LoadCursor(context.Context, string) (string, uint64, error) {
+	return c.sig, 1, nil
+}
+func (fakeCursors) SaveCursor(context.Context, string, string, uint64) error { return nil }
+
+func TestRecoverGapBounded(t *testing.T) {
+	defer func(d time.Duration) { recoveryTimeout = d }(recoveryTimeout)
+	recoveryTimeout = 300 * time.Millisecond
+
+	const missed = 30
+	var mu sync.Mutex
+	looked := map[string]bool{} // signatures asked for with getTransaction
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var result any // getTransaction: null, the node never has it
+		switch req.Method {
+		case "getSignaturesForAddress":
+			var sigs []rpc.SignatureInfo
+			for i := missed; i > 0; i-- { // newest first
+				sigs = append(sigs, rpc.SignatureInfo{Signature: fmt.Sprintf("S%d", i), Slot: uint64(10 + i)})
+			}
+			result = sigs
+		case "getTransaction":
+			mu.Lock()
+			looked[req.Params[0].(string)] = true
+			mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer srv.Close()
+
+	bus := NewBus()
+	events := bus.Subscribe("t", missed+1, DropNewest)
+	s := NewSubscriber("confirmed", "W", ModeAccount)
+	s.bus, s.rpc, s.lookups, s.cursors = bus, rpc.New(srv.URL), make(limiter, maxLookups), fakeCursors{sig: "S0"}
+	s.shouldOpen.Store(true)
+
+	start := time.Now()
+	s.recoverGap()
+	if d := time.Since(start); d > recoveryTimeout+txLookupTimeout/2 {
+		t.Errorf("recoverGap took %s with a %s timeout", d, recoveryTimeout)
+	}
+
+	// Every missed transaction is replayed, in chain order, summaries or not.
+	for i := 1; i <= missed; i++ {
+		select {
+		case e := <-events.C():
+			if e.Kind != KindRecovered || e.Signature != fmt.Sprintf("S%d", i) {
+				t.Fatalf("event %d: %s %s", i, e.Kind, e.Signature)
+			}
+		default:
+			t.Fatalf("only %d of %d transactions replayed", i-1, missed)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for sig := range looked {
+		var n int
+		fmt.Sscanf(sig, "S%d", &n)
+		if n <= missed-maxBackfillDecoded {
+			t.Errorf("looked up %s, older than the newest %d", sig, maxBackfillDecoded)
+		}
+	}
+}
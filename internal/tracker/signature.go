@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/rpc"
+	"github.com/0xsamyy/solwatch/internal/txdecode"
+)
+
+const (
+	// signatureLookupTimeout bounds how long an account alert may wait for
+	// its transaction signature before falling back to the account link.
+	signatureLookupTimeout = 5 * time.Second
+	// signatureLookupInterval is the pause between lookups while the node
+	// has not yet indexed the triggering transaction.
+	signatureLookupInterval = 500 * time.Millisecond
+	// txLookupTimeout bounds how long an alert may wait for getTransaction
+	// before it is sent without a decoded summary.
+	txLookupTimeout = 8 * time.Second
+)
+
+// resolveSignature asks the HTTP RPC for the newest transaction touching
+// the wallet at or after slot. It returns "" on timeout or error. Every
+// call holds one of the Manager's lookup slots.
+func (s *Subscriber) resolveSignature(slot uint64) string {
+	ctx, cancel := context.WithTimeout(context.Background(), signatureLookupTimeout)
+	defer cancel()
+
+	for {
+		if !s.lookups.acquire(ctx) {
+			return ""
+		}
+		sigs, err := s.rpc.GetSignaturesForAddress(ctx, s.addr, rpc.SignaturesOpts{
+			Limit:          1,
+			MinContextSlot: slot,
+			Commitment:     "confirmed", // processed is not supported here
+		})
+		s.lookups.release()
+		if err == nil && len(sigs) > 0 && sigs[0].Slot >= slot {
+			return sigs[0].Signature
+		}
+		if err != nil && ctx.Err() == nil {
+			s.log.Warn("signature lookup", "error", err)
+		}
+
+		// Not indexed yet (or node behind): retry until the deadline.
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-time.After(signatureLookupInterval):
+		}
+	}
+}
+
+// describeTx fetches sig with getTransaction and classifies it for the
+// wallet. It returns nil on timeout (txLookupTimeout, or ctx's deadline if
+// sooner) or error.
+func (s *Subscriber) describeTx(ctx context.Context, sig string) *txdecode.Summary {
+	ctx, cancel := context.WithTimeout(ctx, txLookupTimeout)
+	defer cancel()
+
+	for {
+		if !s.lookups.acquire(ctx) {
+			return nil
+		}
+		raw, err := s.rpc.GetTransaction(ctx, sig)
+		s.lookups.release()
+		if err == nil {
+			sum, derr := txdecode.Decode(s.addr, raw)
+			if derr == nil {
+				return &sum
+			}
+			if !errors.Is(derr, txdecode.ErrNoTransaction) {
+				s.log.Warn("decode tx", "signature", sig, "error", derr)
+				return nil
+			}
+		} else if ctx.Err() == nil {
+			s.log.Warn("getTransaction", "signature", sig, "error", err)
+		}
+
+		// Not confirmed yet: retry until the deadline.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(signatureLookupInterval):
+		}
+	}
+}
@@ -3,250 +3,309 @@ package tracker
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
+	"math/big"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
-
-	"github.com/0xsamyy/solwatch/internal/util"
+	"github.com/0xsamyy/solwatch/internal/metrics"
+	"github.com/0xsamyy/solwatch/internal/rpc"
 )
 
-// ActivityNotify is a package-level callback that, if set, will be called
-// whenever a subscription receives an update. The string should be a fully
-// formatted HTML message for Telegram (one line).
-//
-// Set this from the Telegram handler or the manager on initialization:
-//
-//   tracker.ActivityNotify = func(text string) { /* send via Telegram */ }
-//
-var ActivityNotify func(text string)
-
-// Subscriber maintains a single accountSubscribe connection for one wallet.
+// Subscriber represents one tracked wallet. It no longer owns a socket:
+// its upstream subscriptions are carried by a shared connection in the Pool.
 type Subscriber struct {
-	wss        string // Helius (or Solana RPC) WebSocket URL
 	addr       string // wallet public key (base58, validated upstream)
 	commitment string // processed|confirmed|finalized
+	mode       Mode   // account|logs
+	tokens     bool   // also watch the wallet's SPL token accounts
+	log        *slog.Logger
+
+	// where events are published
+	bus *Bus
+	// optional HTTP RPC used to resolve the triggering transaction
+	rpc *rpc.Client
+	// lookups bounds concurrent RPC lookups (shared by the Manager's
+	// subscribers); work runs enrichment and publishing in push order
+	lookups limiter
+	work    dispatcher
+	// optional cursor persistence for gap recovery
+	cursors    CursorStore
+	recovering atomic.Bool
 
 	// state flags
-	open       atomic.Bool // true when the websocket is open
-	shouldOpen atomic.Bool // desired state (false after Stop)
+	connected  atomic.Bool   // its connection's socket is up
+	open       atomic.Bool   // main subscription acknowledged ("subscribed")
+	shouldOpen atomic.Bool   // desired state (false after Stop)
+	failed     atomic.Bool   // rejected with a fatal error; no more retries
+	subID      atomic.Uint64 // upstream id of the main subscription
+
+	// counters for /status and flapping detection
+	connects    atomic.Uint64 // main subscription acknowledged
+	disconnects atomic.Uint64 // acknowledged subscription lost with its socket
+	lastNotify  atomic.Int64  // UnixNano of the last push; 0 = none
+	backoff     atomic.Int64  // current reconnect delay of its connection
 
-	// internals
-	stopOnce sync.Once
-	stopCh   chan struct{}
+	// last known balance, used to render deltas in alerts
+	mu           sync.Mutex
+	lamports     uint64
+	haveLamports bool
+	tokenAmounts map[string]*big.Int // token account -> last raw amount
+	seen         map[string]struct{} // recently alerted signatures
+	seenOrder    []string
+	lastErr      *RPCError
+	lastErrAt    time.Time
+	failReason   string
+	failedAt     time.Time
 }
 
-// NewSubscriber creates a new Subscriber. It does not start it; call Run().
-func NewSubscriber(wss, commitment, addr string) *Subscriber {
+// NewSubscriber creates a new Subscriber. Hand it to Pool.Add to start it.
+func NewSubscriber(commitment, addr string, mode Mode) *Subscriber {
+	if mode == "" {
+		mode = ModeAccount
+	}
 	s := &Subscriber{
-		wss:        strings.TrimSpace(wss),
 		addr:       strings.TrimSpace(addr),
 		commitment: strings.TrimSpace(commitment),
-		stopCh:     make(chan struct{}),
+		mode:       mode,
+		log:        logger.With("wallet", strings.TrimSpace(addr)),
+
+		tokenAmounts: make(map[string]*big.Int),
+		seen:         make(map[string]struct{}),
 	}
 	s.shouldOpen.Store(true)
 	return s
 }
 
+// IsOpen reports whether the main subscription is acknowledged (subscribed).
 func (s *Subscriber) IsOpen() bool       { return s.open.Load() }
+func (s *Subscriber) IsConnected() bool  { return s.connected.Load() }
 func (s *Subscriber) ShouldBeOpen() bool { return s.shouldOpen.Load() }
+func (s *Subscriber) Failed() bool       { return s.failed.Load() }
+func (s *Subscriber) Mode() Mode         { return s.mode }
 
-// Stop signals the subscriber to cease reconnecting and close gracefully.
-func (s *Subscriber) Stop() {
-	s.stopOnce.Do(func() {
-		s.shouldOpen.Store(false)
-		close(s.stopCh)
-	})
+// SubscriptionID is the upstream id of the acknowledged main subscription.
+func (s *Subscriber) SubscriptionID() uint64 { return s.subID.Load() }
+
+// LastError returns the most recent JSON-RPC error for any of the
+// subscriber's streams, if any.
+func (s *Subscriber) LastError() (*RPCError, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr, s.lastErrAt
 }
 
-// Run is a long-running method: it connects, subscribes, reads updates,
-// and auto-reconnects with exponential backoff + jitter until Stop() or ctx cancel.
-func (s *Subscriber) Run(ctx context.Context) {
-	bo := util.NewBackoff(1*time.Second, 30*time.Second, 2.0, 0.2)
+// subscribed records a successful ACK of the main subscription.
+func (s *Subscriber) subscribed(id uint64) {
+	s.subID.Store(id)
+	if !s.open.Swap(true) {
+		s.connects.Add(1)
+	}
+}
 
-	for {
-		// Exit conditions
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.stopCh:
-			return
-		default:
-		}
+// SubscriberStats is the per-wallet view for /status and flapping reports.
+type SubscriberStats struct {
+	Addr             string        `json:"addr"`
+	Mode             Mode          `json:"mode"`
+	Open             bool          `json:"open"`
+	Connected        bool          `json:"connected"`
+	Failed           bool          `json:"failed"`
+	Connects         uint64        `json:"connects"`
+	Disconnects      uint64        `json:"disconnects"`
+	LastError        string        `json:"last_error,omitempty"`
+	LastErrorAt      time.Time     `json:"last_error_at,omitempty"`
+	LastNotification time.Time     `json:"last_notification,omitempty"`
+	Backoff          time.Duration `json:"backoff"` // current reconnect delay; 0 while connected
+}
 
-		// Dial
-		dialer := websocket.Dialer{
-			Proxy:            http.ProxyFromEnvironment,
-			HandshakeTimeout: 12 * time.Second,
-			EnableCompression: true,
-		}
-		conn, _, err := dialer.DialContext(ctx, s.wss, nil)
-		if err != nil {
-			wait := bo.Next()
-			log.Printf("[sub %s] dial error: %v; retry in %s", s.prettyAddr(), err, wait)
-			select {
-			case <-ctx.Done():
-				return
-			case <-s.stopCh:
-				return
-			case <-time.After(wait):
-				continue
-			}
-		}
-		// Connected
-		s.open.Store(true)
-		bo.Reset()
-
-		// Ensure proper close on this iteration
-		closed := make(chan struct{})
-		go func() {
-			<-s.stopCh
-			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "stop"), time.Now().Add(2*time.Second))
-			_ = conn.Close()
-			close(closed)
-		}()
-
-		// Keep read deadlines fresh via pong handler
-		_ = conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		conn.SetPongHandler(func(string) error {
-			return conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		})
+// Stats returns the subscriber's counters.
+func (s *Subscriber) Stats() SubscriberStats {
+	st := SubscriberStats{
+		Addr:        s.addr,
+		Mode:        s.mode,
+		Open:        s.IsOpen(),
+		Connected:   s.IsConnected(),
+		Failed:      s.Failed(),
+		Connects:    s.connects.Load(),
+		Disconnects: s.disconnects.Load(),
+		Backoff:     time.Duration(s.backoff.Load()),
+	}
+	if n := s.lastNotify.Load(); n != 0 {
+		st.LastNotification = time.Unix(0, n).UTC()
+	}
+	if e, at := s.LastError(); e != nil {
+		st.LastError, st.LastErrorAt = e.Error(), at.UTC()
+	}
+	return st
+}
+
+func (s *Subscriber) recordError(e *RPCError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr, s.lastErrAt = e, time.Now()
+}
+
+// markFailed stops the subscriber from being counted as merely reconnecting.
+func (s *Subscriber) markFailed(reason string) {
+	s.mu.Lock()
+	s.failReason, s.failedAt = reason, time.Now()
+	s.mu.Unlock()
+	s.failed.Store(true)
+	s.open.Store(false)
+}
+
+// failure returns the reason the subscriber gave up, if it did.
+func (s *Subscriber) failure() (Failure, bool) {
+	if !s.failed.Load() {
+		return Failure{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Failure{Addr: s.addr, Reason: s.failReason, Since: s.failedAt}, true
+}
+
+// Stop marks the subscriber as no longer wanted. The caller is expected to
+// remove it from the Pool, which unsubscribes it upstream.
+func (s *Subscriber) Stop() {
+	s.shouldOpen.Store(false)
+}
 
-		// Subscribe
-		subMsg := map[string]any{
-			"jsonrpc": "2.0",
-			"id":      1,
-			"method":  "accountSubscribe",
-			"params": []any{
-				s.addr,
-				map[string]any{
-					"encoding":   "jsonParsed",
-					"commitment": s.commitment,
-				},
+// streams lists the upstream subscriptions this wallet needs.
+func (s *Subscriber) streams() []*stream {
+	out := s.mainStreams()
+	if s.tokens {
+		out = append(out, s.tokenStreams()...)
+	}
+	return out
+}
+
+// mainStreams is the account- or logs-mode subscription for the wallet itself.
+func (s *Subscriber) mainStreams() []*stream {
+	if s.mode == ModeLogs {
+		return []*stream{{
+			sub:    s,
+			main:   true,
+			method: "logsSubscribe",
+			unsub:  "logsUnsubscribe",
+			params: []any{
+				map[string]any{"mentions": []string{s.addr}},
+				map[string]any{"commitment": s.commitment},
 			},
-		}
-		if err := conn.WriteJSON(subMsg); err != nil {
-			log.Printf("[sub %s] write subscribe error: %v", s.prettyAddr(), err)
-			s.open.Store(false)
-			_ = conn.Close()
-			wait := bo.Next()
-			select {
-			case <-ctx.Done():
-				return
-			case <-s.stopCh:
-				return
-			case <-time.After(wait):
-				continue
-			}
-		}
+		}}
+	}
+	return []*stream{{
+		sub:    s,
+		main:   true,
+		method: "accountSubscribe",
+		unsub:  "accountUnsubscribe",
+		params: []any{
+			s.addr,
+			map[string]any{
+				"encoding":   "jsonParsed",
+				"commitment": s.commitment,
+			},
+		},
+	}}
+}
 
-		// Ping loop to keep the connection alive (every 20s)
-		pingStop := make(chan struct{})
-		go func() {
-			t := time.NewTicker(20 * time.Second)
-			defer t.Stop()
-			for {
-				select {
-				case <-pingStop:
-					return
-				case <-s.stopCh:
-					return
-				case <-ctx.Done():
-					return
-				case <-t.C:
-					_ = conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(5*time.Second))
-				}
-			}
-		}()
-
-		// Read loop: any push update => "activity detected"
-		readErr := func() error {
-			for {
-				// Read
-				_, msg, err := conn.ReadMessage()
-				if err != nil {
-					return err
-				}
-				// Parse minimal JSON to distinguish sub ack vs. update
-				if isNotif(msg) {
-					// 🚨 Activity Detected: <a href="...">ABCD...WXYZ</a>
-					short := s.prettyAddr()
-					link := fmt.Sprintf(`🚨 <b>Activity Detected:</b> <a href="https://solscan.io/account/%s">%s</a>`, s.addr, short)
-					if ActivityNotify != nil {
-						ActivityNotify(link)
-					}
-				}
+// handle is invoked by the owning connection for every push routed to one
+// of this subscriber's streams; method is the stream's subscribe method.
+func (s *Subscriber) handle(method string, result json.RawMessage) {
+	received := time.Now().UTC()
+	s.lastNotify.Store(received.UnixNano())
+	s.log.Debug("notification", "method", method)
 
-			}
-		}()
-
-		// Tear down and backoff
-		close(pingStop)
-		s.open.Store(false)
-		_ = conn.Close()
-
-		if readErr != nil {
-			wait := bo.Next()
-			log.Printf("[sub %s] read error: %v; reconnect in %s", s.prettyAddr(), readErr, wait)
-			select {
-			case <-ctx.Done():
-				return
-			case <-s.stopCh:
-				return
-			case <-time.After(wait):
-				continue
-			}
+	if method == "programSubscribe" {
+		s.handleToken(result, received)
+		return
+	}
+	if method == "logsSubscribe" {
+		n, err := decodeLogsNotification(result)
+		if err != nil {
+			s.log.Warn("decode notification", "method", method, "error", err)
+			return
 		}
-
-		// Also wait for stop-close (if Stop() was invoked during connection)
-		select {
-		case <-closed:
+		if !s.markProcessed(n.Signature, n.Slot) {
+			return // already reported (e.g. by gap recovery)
+		}
+		e := Event{
+			Wallet:    s.addr,
+			Kind:      KindTx,
+			Slot:      n.Slot,
+			Signature: n.Signature,
+			Raw:       result,
+			Time:      received,
+			Tx:        &TxStatus{Failed: n.Failed(), Err: n.Err, Logs: n.Logs},
+		}
+		if s.rpc == nil {
+			s.emit(e)
 			return
-		default:
 		}
+		s.enqueue(func(enrich bool) {
+			if enrich {
+				e.Summary = s.describeTx(context.Background(), n.Signature)
+			}
+			s.publish(e)
+		})
+		return
 	}
-}
 
-// isNotif determines if a raw JSON message is a subscription notification.
-// For Solana JSON-RPC, subscription pushes generally carry a "method" field
-// like "accountNotification" (or vendor variant). Initial subscribe success
-// has "result" with a subscription id.
-//
-// Heuristic: treat messages with "method" AND "params" as updates;
-// messages with top-level "result" are subscribe ACKs.
-func isNotif(raw []byte) bool {
-	var m map[string]any
-	if err := json.Unmarshal(raw, &m); err != nil {
-		return false
+	e := Event{Wallet: s.addr, Kind: KindAccount, Raw: result, Time: received}
+	n, err := decodeAccountNotification(result)
+	if err != nil {
+		s.log.Warn("decode notification", "method", method, "error", err)
+		s.emit(e) // still worth an alert, just without details
+		return
 	}
-	if _, ok := m["result"]; ok {
-		// subscribe ACK or other call result
-		return false
+	e.Slot = n.Slot
+
+	// Update the remembered balance before any async work so deltas stay ordered.
+	s.mu.Lock()
+	e.Account = &AccountChange{Lamports: n.Lamports, PrevLamports: s.lamports, HavePrev: s.haveLamports}
+	s.lamports, s.haveLamports = n.Lamports, true
+	s.mu.Unlock()
+
+	if s.rpc == nil {
+		s.emit(e)
+		return
 	}
-	if meth, ok := m["method"].(string); ok {
-		if strings.Contains(strings.ToLower(meth), "account") {
-			if _, ok := m["params"]; ok {
-				return true
-			}
+	// Resolve the signature off the read loop; publish without it on timeout
+	// or when the wallet's backlog is too long for lookups.
+	s.enqueue(func(enrich bool) {
+		if !enrich {
+			s.publish(e)
+			return
 		}
-	}
-	// Some providers omit "method" for pushes; fallback: if "params" exists with "result", treat as update.
-	if params, ok := m["params"].(map[string]any); ok {
-		if _, has := params["result"]; has {
-			return true
+		if sig := s.resolveSignature(n.Slot); sig != "" {
+			if !s.markProcessed(sig, n.Slot) {
+				return // already reported (e.g. by gap recovery)
+			}
+			e.Signature = sig
+			e.Summary = s.describeTx(context.Background(), sig)
 		}
+		s.publish(e)
+	})
+}
+
+// enqueue hands job to the wallet's dispatcher. Pushes are never published
+// on the connection's read loop: a slow sink or outbox write would stall
+// every wallet sharing the socket and can miss its ping deadline.
+func (s *Subscriber) enqueue(job func(enrich bool)) {
+	if !s.work.do(job) {
+		metrics.EventsDropped.Inc()
+		s.log.Warn("dispatch backlog full; event dropped")
 	}
-	return false
 }
 
-func (s *Subscriber) prettyAddr() string {
-    if len(s.addr) <= 8 {
-        return s.addr
-    }
-    return s.addr[:4] + "..." + s.addr[len(s.addr)-4:]
+// emit publishes e off the read loop, after the wallet's earlier events.
+func (s *Subscriber) emit(e Event) {
+	s.enqueue(func(bool) { s.publish(e) })
+}
+
+func (s *Subscriber) publish(e Event) {
+	if s.bus != nil {
+		s.bus.Publish(e)
+	}
 }
@@ -0,0 +1,170 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/txdecode"
+)
+
+// tokenAccountSize is the size of a classic SPL Token account. Token-2022
+// accounts may carry extensions, so only the owner filter is used there.
+const tokenAccountSize = 165
+
+// tokenOwnerOffset is the byte offset of the owner pubkey in a token account.
+const tokenOwnerOffset = 32
+
+// TokenAccountNotification is the typed form of a programNotification for
+// an SPL token account owned by a tracked wallet.
+type TokenAccountNotification struct {
+	Slot     uint64 `json:"slot"`
+	Account  string `json:"account"` // token account pubkey
+	Mint     string `json:"mint"`
+	Owner    string `json:"owner"`
+	Amount   string `json:"amount"` // raw base units
+	Decimals int    `json:"decimals"`
+}
+
+// tokenAccountInfo is the jsonParsed shape of an SPL token account.
+type tokenAccountInfo struct {
+	Data struct {
+		Parsed struct {
+			Type string `json:"type"`
+			Info struct {
+				Mint        string `json:"mint"`
+				Owner       string `json:"owner"`
+				TokenAmount struct {
+					Amount   string `json:"amount"`
+					Decimals int    `json:"decimals"`
+				} `json:"tokenAmount"`
+			} `json:"info"`
+		} `json:"parsed"`
+	} `json:"data"`
+}
+
+// decodeTokenAccountNotification decodes params.result of a programNotification:
+//
+//	{"context":{"slot":N},"value":{"pubkey":..,"account":{"data":{"parsed":..}}}}
+func decodeTokenAccountNotification(raw json.RawMessage) (TokenAccountNotification, error) {
+	var env struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value struct {
+			Pubkey  string           `json:"pubkey"`
+			Account tokenAccountInfo `json:"account"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return TokenAccountNotification{}, fmt.Errorf("decode program notification: %w", err)
+	}
+	p := env.Value.Account.Data.Parsed
+	if p.Type != "account" || p.Info.Mint == "" {
+		// e.g. a closed account (no parsed data) or a non-account type.
+		return TokenAccountNotification{}, fmt.Errorf("program notification for %s is not a token account", shorten(env.Value.Pubkey))
+	}
+	return TokenAccountNotification{
+		Slot:     env.Context.Slot,
+		Account:  env.Value.Pubkey,
+		Mint:     p.Info.Mint,
+		Owner:    p.Info.Owner,
+		Amount:   p.Info.TokenAmount.Amount,
+		Decimals: p.Info.TokenAmount.Decimals,
+	}, nil
+}
+
+// tokenStreams are the programSubscribe streams covering the wallet's
+// token accounts on both the Token and Token-2022 programs.
+func (s *Subscriber) tokenStreams() []*stream {
+	owner := map[string]any{"memcmp": map[string]any{"offset": tokenOwnerOffset, "bytes": s.addr}}
+	mk := func(program string, filters []any) *stream {
+		return &stream{
+			sub:    s,
+			method: "programSubscribe",
+			unsub:  "programUnsubscribe",
+			params: []any{
+				program,
+				map[string]any{
+					"encoding":   "jsonParsed",
+					"commitment": s.commitment,
+					"filters":    filters,
+				},
+			},
+		}
+	}
+	return []*stream{
+		mk(txdecode.TokenProgram, []any{map[string]any{"dataSize": tokenAccountSize}, owner}),
+		mk(txdecode.Token2022Program, []any{owner}),
+	}
+}
+
+// handleToken publishes a KindToken event when a token account balance moved.
+func (s *Subscriber) handleToken(result json.RawMessage, received time.Time) {
+	n, err := decodeTokenAccountNotification(result)
+	if err != nil {
+		s.log.Warn("decode notification", "method", "programSubscribe", "error", err)
+		return
+	}
+	cur, ok := new(big.Int).SetString(n.Amount, 10)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	prev, havePrev := s.tokenAmounts[n.Account]
+	s.tokenAmounts[n.Account] = cur
+	s.mu.Unlock()
+
+	if havePrev && prev.Cmp(cur) == 0 {
+		return // delegate/state change without a balance move
+	}
+
+	tc := &TokenChange{TokenAccount: n.Account, Mint: n.Mint, Amount: n.Amount, Decimals: n.Decimals}
+	if havePrev {
+		tc.PrevAmount = prev.String()
+	}
+	s.emit(Event{Wallet: s.addr, Kind: KindToken, Slot: n.Slot, Raw: result, Time: received, Token: tc})
+}
+
+// seedTokenBalances loads current token balances so the first change of
+// each token account can be reported as a delta.
+func (s *Subscriber) seedTokenBalances() {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	for _, program := range []string{txdecode.TokenProgram, txdecode.Token2022Program} {
+		var res struct {
+			Value []struct {
+				Pubkey  string           `json:"pubkey"`
+				Account tokenAccountInfo `json:"account"`
+			} `json:"value"`
+		}
+		if !s.lookups.acquire(ctx) {
+			return
+		}
+		err := s.rpc.Call(ctx, "getTokenAccountsByOwner", []any{
+			s.addr,
+			map[string]any{"programId": program},
+			map[string]any{"encoding": "jsonParsed", "commitment": s.commitment},
+		}, &res)
+		s.lookups.release()
+		if err != nil {
+			s.log.Warn("seed token balances", "program", program, "error", err)
+			continue
+		}
+
+		s.mu.Lock()
+		for _, v := range res.Value {
+			if _, seen := s.tokenAmounts[v.Pubkey]; seen {
+				continue // a live notification already arrived
+			}
+			if amt, ok := new(big.Int).SetString(v.Account.Data.Parsed.Info.TokenAmount.Amount, 10); ok {
+				s.tokenAmounts[v.Pubkey] = amt
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,394 @@
+// Package txdecode classifies a Solana transaction (getTransaction with
+// jsonParsed encoding) from the point of view of one wallet and renders a
+// one-line, human-readable summary such as "swapped 2 SOL → 1.2M BONK".
+//
+// Decode is a pure function over the raw JSON result, so recorded
+// transactions can be fed to it directly.
+package txdecode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Class is the coarse category of a transaction.
+type Class string
+
+const (
+	ClassSOLTransfer   Class = "sol_transfer"
+	ClassTokenTransfer Class = "token_transfer"
+	ClassSwap          Class = "swap"
+	ClassNFTTransfer   Class = "nft_transfer"
+	ClassStake         Class = "stake"
+	ClassUnknown       Class = "unknown"
+)
+
+// Summary is the decoded view of one transaction for one wallet.
+type Summary struct {
+	Class  Class  `json:"class"`
+	Text   string `json:"text"` // e.g. "swapped 2 SOL → 1.2M BONK on Jupiter"
+	Failed bool   `json:"failed"`
+}
+
+// dustLamports is the native SOL movement ignored when a swap is
+// token-to-token (rent for new token accounts, priority fees, tips).
+const dustLamports = 10_000_000 // 0.01 SOL
+
+// ErrNoTransaction is returned for a null getTransaction result.
+var ErrNoTransaction = errors.New("transaction not available")
+
+// Decode classifies raw (the "result" of getTransaction) for wallet.
+func Decode(wallet string, raw []byte) (Summary, error) {
+	if s := strings.TrimSpace(string(raw)); s == "" || s == "null" {
+		return Summary{}, ErrNoTransaction
+	}
+	var t transaction
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return Summary{}, fmt.Errorf("decode transaction: %w", err)
+	}
+	if t.Meta == nil {
+		return Summary{}, errors.New("transaction without meta")
+	}
+
+	sum := classify(wallet, &t)
+	if e := strings.TrimSpace(string(t.Meta.Err)); e != "" && e != "null" {
+		sum.Failed = true
+		sum.Text = "failed: " + sum.Text
+	}
+	return sum, nil
+}
+
+// assetDelta is a signed balance change of one asset for the wallet.
+type assetDelta struct {
+	mint     string // "" for native SOL
+	delta    *big.Int
+	decimals int
+}
+
+func classify(wallet string, t *transaction) Summary {
+	ixs := t.allInstructions()
+	sol := solDelta(wallet, t)
+	tokens := tokenDeltas(wallet, t)
+
+	// Stake actions first: they also move SOL and would look like transfers.
+	for _, ix := range ixs {
+		if ix.ProgramID == StakeProgram {
+			return stakeSummary(ix)
+		}
+	}
+
+	// Swaps: a DEX/aggregator program ran and the wallet gave one asset for another.
+	for _, ix := range ixs {
+		venue, ok := swapPrograms[ix.ProgramID]
+		if !ok {
+			continue
+		}
+		if s, ok := swapSummary(sol, tokens, venue); ok {
+			return s
+		}
+		break
+	}
+
+	// Token movements: NFT if a single 0-decimal unit moved.
+	if len(tokens) > 0 {
+		d := tokens[0]
+		cp := tokenCounterparty(wallet, d, t)
+		if d.decimals == 0 && new(big.Int).Abs(d.delta).Cmp(big.NewInt(1)) == 0 {
+			return Summary{Class: ClassNFTTransfer, Text: direction(d.delta.Sign(), "NFT "+symbol(d.mint), cp)}
+		}
+		return Summary{Class: ClassTokenTransfer, Text: direction(d.delta.Sign(), formatAsset(d), cp)}
+	}
+
+	// Plain SOL transfers via the System program.
+	var sent, received uint64
+	var sentTo, receivedFrom string
+	for _, ix := range ixs {
+		if ix.ProgramID != SystemProgram || (ix.parsedType() != "transfer" && ix.parsedType() != "transferWithSeed") {
+			continue
+		}
+		lamports := ix.infoUint("lamports")
+		switch wallet {
+		case ix.infoString("source"):
+			sent += lamports
+			sentTo = ix.infoString("destination")
+		case ix.infoString("destination"):
+			received += lamports
+			receivedFrom = ix.infoString("source")
+		}
+	}
+	switch {
+	case sent > received:
+		d := assetDelta{delta: new(big.Int).SetUint64(sent - received), decimals: 9}
+		return Summary{Class: ClassSOLTransfer, Text: direction(-1, formatAsset(d), sentTo)}
+	case received > sent:
+		d := assetDelta{delta: new(big.Int).SetUint64(received - sent), decimals: 9}
+		return Summary{Class: ClassSOLTransfer, Text: direction(1, formatAsset(d), receivedFrom)}
+	}
+
+	text := "unknown tx"
+	if names := programNames(ixs); len(names) > 0 {
+		text += " via " + strings.Join(names, ", ")
+	}
+	if sol != nil && sol.delta.Sign() != 0 {
+		text += " (" + signed(*sol) + ")"
+	}
+	return Summary{Class: ClassUnknown, Text: text}
+}
+
+// solDelta returns the wallet's native SOL change, excluding the fee it paid.
+func solDelta(wallet string, t *transaction) *assetDelta {
+	for i, k := range t.Transaction.Message.AccountKeys {
+		if k.Pubkey != wallet {
+			continue
+		}
+		if i >= len(t.Meta.PreBalances) || i >= len(t.Meta.PostBalances) {
+			return nil
+		}
+		d := new(big.Int).SetUint64(t.Meta.PostBalances[i])
+		d.Sub(d, new(big.Int).SetUint64(t.Meta.PreBalances[i]))
+		if i == 0 { // fee payer
+			d.Add(d, new(big.Int).SetUint64(t.Meta.Fee))
+		}
+		return &assetDelta{delta: d, decimals: 9}
+	}
+	return nil
+}
+
+// tokenDeltas returns the wallet's non-zero token changes, largest first.
+func tokenDeltas(wallet string, t *transaction) []assetDelta {
+	byMint := map[string]*assetDelta{}
+	add := func(tb tokenBalance, sign int) {
+		if tb.Owner != wallet {
+			return
+		}
+		d, ok := byMint[tb.Mint]
+		if !ok {
+			d = &assetDelta{mint: tb.Mint, delta: new(big.Int), decimals: tb.UITokenAmount.Decimals}
+			byMint[tb.Mint] = d
+		}
+		if sign > 0 {
+			d.delta.Add(d.delta, tb.amount())
+		} else {
+			d.delta.Sub(d.delta, tb.amount())
+		}
+	}
+	for _, tb := range t.Meta.PreTokenBalances {
+		add(tb, -1)
+	}
+	for _, tb := range t.Meta.PostTokenBalances {
+		add(tb, +1)
+	}
+
+	out := make([]assetDelta, 0, len(byMint))
+	for _, d := range byMint {
+		if d.delta.Sign() != 0 {
+			out = append(out, *d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := new(big.Int).Abs(out[i].delta).Cmp(new(big.Int).Abs(out[j].delta)); c != 0 {
+			return c > 0
+		}
+		return out[i].mint < out[j].mint
+	})
+	return out
+}
+
+// tokenCounterparty finds the other owner whose balance of d.mint moved the
+// opposite way, or "" if there is none.
+func tokenCounterparty(wallet string, d assetDelta, t *transaction) string {
+	pre := map[int]tokenBalance{}
+	for _, tb := range t.Meta.PreTokenBalances {
+		pre[tb.AccountIndex] = tb
+	}
+	for _, post := range t.Meta.PostTokenBalances {
+		if post.Mint != d.mint || post.Owner == wallet || post.Owner == "" {
+			continue
+		}
+		delta := post.amount()
+		if p, ok := pre[post.AccountIndex]; ok {
+			delta.Sub(delta, p.amount())
+		}
+		if delta.Sign() != 0 && delta.Sign() != d.delta.Sign() {
+			return post.Owner
+		}
+	}
+	return ""
+}
+
+func swapSummary(sol *assetDelta, tokens []assetDelta, venue string) (Summary, bool) {
+	var legs []assetDelta
+	var wsol *assetDelta
+	for i := range tokens {
+		if tokens[i].mint == WrappedSOLMint {
+			wsol = &tokens[i]
+			continue
+		}
+		legs = append(legs, tokens[i])
+	}
+	// Native SOL (or wSOL held across the tx) counts as a leg unless it is dust.
+	switch {
+	case sol != nil && new(big.Int).Abs(sol.delta).Cmp(big.NewInt(dustLamports)) > 0:
+		legs = append(legs, *sol)
+	case wsol != nil:
+		legs = append(legs, *wsol)
+	}
+
+	var in, out *assetDelta // in = received, out = spent
+	for i := range legs {
+		l := &legs[i]
+		switch {
+		case l.delta.Sign() > 0 && (in == nil || biggerShare(l, in)):
+			in = l
+		case l.delta.Sign() < 0 && (out == nil || biggerShare(l, out)):
+			out = l
+		}
+	}
+	if in == nil || out == nil {
+		return Summary{}, false
+	}
+	spent := assetDelta{mint: out.mint, delta: new(big.Int).Neg(out.delta), decimals: out.decimals}
+	return Summary{
+		Class: ClassSwap,
+		Text:  fmt.Sprintf("swapped %s → %s on %s", formatAsset(spent), formatAsset(*in), venue),
+	}, true
+}
+
+// biggerShare compares two deltas by absolute UI amount.
+func biggerShare(a, b *assetDelta) bool {
+	return math.Abs(uiAmount(*a)) > math.Abs(uiAmount(*b))
+}
+
+func stakeSummary(ix instruction) Summary {
+	action := ix.parsedType()
+	if action == "" {
+		action = "instruction"
+	}
+	text := "stake " + action
+	if l := ix.infoUint("lamports"); l > 0 {
+		text += " " + formatAsset(assetDelta{delta: new(big.Int).SetUint64(l), decimals: 9})
+	}
+	if acct := ix.infoString("stakeAccount"); acct != "" {
+		text += " (stake account " + shorten(acct) + ")"
+	}
+	return Summary{Class: ClassStake, Text: text}
+}
+
+// direction renders "received X from Y" / "sent X to Y".
+func direction(sign int, what, counterparty string) string {
+	if sign >= 0 {
+		if counterparty != "" {
+			return "received " + what + " from " + shorten(counterparty)
+		}
+		return "received " + what
+	}
+	if counterparty != "" {
+		return "sent " + what + " to " + shorten(counterparty)
+	}
+	return "sent " + what
+}
+
+// programNames lists distinct invoked programs (by parsed name or short id).
+func programNames(ixs []instruction) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, ix := range ixs {
+		name := ix.Program
+		if name == "" {
+			name = shorten(ix.ProgramID)
+		}
+		if v, ok := swapPrograms[ix.ProgramID]; ok {
+			name = v
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// ----- formatting helpers -----
+
+func uiAmount(d assetDelta) float64 {
+	f, _ := new(big.Float).SetInt(d.delta).Float64()
+	return f / math.Pow10(d.decimals)
+}
+
+// FormatAmount renders a raw token amount (in base units) briefly, e.g. "1.2M".
+func FormatAmount(raw *big.Int, decimals int) string {
+	return compact(math.Abs(uiAmount(assetDelta{delta: raw, decimals: decimals})))
+}
+
+// Symbol returns the ticker of a well-known mint, or the shortened mint.
+func Symbol(mint string) string {
+	return symbol(mint)
+}
+
+// formatAsset renders the absolute amount with its ticker, e.g. "1.2M BONK".
+func formatAsset(d assetDelta) string {
+	return compact(math.Abs(uiAmount(d))) + " " + symbol(d.mint)
+}
+
+// signed renders a delta with an explicit sign, e.g. "-0.5 SOL".
+func signed(d assetDelta) string {
+	if d.delta.Sign() < 0 {
+		return "-" + formatAsset(d)
+	}
+	return "+" + formatAsset(d)
+}
+
+func symbol(mint string) string {
+	if mint == "" {
+		return "SOL"
+	}
+	if s, ok := knownMints[mint]; ok {
+		return s
+	}
+	return shorten(mint)
+}
+
+// compact renders a non-negative amount briefly: 2, 10.4, 1.5K, 1.2M, 0.0042.
+func compact(f float64) string {
+	switch {
+	case f >= 1e9:
+		return trimFloat(f/1e9, 2) + "B"
+	case f >= 1e6:
+		return trimFloat(f/1e6, 2) + "M"
+	case f >= 1e4:
+		return trimFloat(f/1e3, 2) + "K"
+	case f >= 1:
+		return trimFloat(f, 4)
+	case f == 0:
+		return "0"
+	default:
+		// keep four significant digits without switching to exponent form
+		prec := int(-math.Floor(math.Log10(f))) + 3
+		if prec > 12 {
+			prec = 12
+		}
+		return trimFloat(f, prec)
+	}
+}
+
+func trimFloat(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func shorten(s string) string {
+	if len(s) <= 8 {
+		return s
+	}
+	return s[:4] + "..." + s[len(s)-4:]
+}
@@ -0,0 +1,101 @@
+package txdecode
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// wallet is the point of view of every fixture in testdata.
+const wallet = "MASi45ub7Qe4ZE36UT5G6cU4ud8Fhhe4deS4F3cw9KTA"
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		file  string
+		class Class
+		text  string
+	}{
+		{"sol_transfer.json", ClassSOLTransfer, "sent 1.5 SOL to b8dL...dLaY"},
+		{"spl_transfer.json", ClassTokenTransfer, "received 250 USDC from tcSS...X88w"},
+		{"swap.json", ClassSwap, "swapped 2.002 SOL → 1.2M BONK on Jupiter"},
+		{"nft.json", ClassNFTTransfer, "received NFT iF62...wHGo from nsyf...znkm"},
+		{"stake.json", ClassStake, "stake withdraw 3.25 SOL (stake account 6CNc...6JzG)"},
+		{"unknown.json", ClassUnknown, "unknown tx via Comp...1111, dRif...33UH (-0.02 SOL)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := Decode(wallet, raw)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if s.Class != tt.class {
+				t.Errorf("Class = %q, want %q", s.Class, tt.class)
+			}
+			if s.Text != tt.text {
+				t.Errorf("Text = %q, want %q", s.Text, tt.text)
+			}
+			if s.Failed {
+				t.Error("Failed = true, want false")
+			}
+		})
+	}
+}
+
+func TestDecodeFailed(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "sol_transfer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw = bytes.Replace(raw, []byte(`"err": null`), []byte(`"err": {"InstructionError": [1, {"Custom": 1}]}`), 1)
+	s, err := Decode(wallet, raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !s.Failed || s.Text != "failed: sent 1.5 SOL to b8dL...dLaY" {
+		t.Errorf("got %+v, want a failed SOL transfer", s)
+	}
+}
+
+func TestDecodeNull(t *testing.T) {
+	if _, err := Decode(wallet, []byte("null")); !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("err = %v, want ErrNoTransaction", err)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		raw      string
+		decimals int
+		want     string
+	}{
+		{"0", 9, "0"},
+		{"1500000000", 9, "1.5"},
+		{"-1500000000", 9, "1.5"}, // sign is the caller's business
+		{"250000000", 6, "250"},
+		{"1", 0, "1"},
+		{"15000", 0, "15K"},
+		{"-120000000000", 5, "1.2M"},
+		{"123456789", 0, "123.46M"},
+		{"1000000000000000000", 9, "1B"},
+		{"999999999", 9, "1"},
+		{"123456", 9, "0.0001235"},
+		{"42", 9, "0.000000042"},
+		{"1", 12, "0.000000000001"},
+	}
+	for _, tt := range tests {
+		raw, ok := new(big.Int).SetString(tt.raw, 10)
+		if !ok {
+			t.Fatalf("bad raw amount %q", tt.raw)
+		}
+		if got := FormatAmount(raw, tt.decimals); got != tt.want {
+			t.Errorf("FormatAmount(%s, %d) = %q, want %q", tt.raw, tt.decimals, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package txdecode
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// Well-known program ids.
+const (
+	SystemProgram    = "11111111111111111111111111111111"
+	StakeProgram     = "Stake11111111111111111111111111111111111111"
+	TokenProgram     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	Token2022Program = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+
+	// WrappedSOLMint is the wSOL mint; swaps through it are reported as SOL.
+	WrappedSOLMint = "So11111111111111111111111111111111111111112"
+)
+
+// swapPrograms maps DEX / aggregator program ids to a display name.
+var swapPrograms = map[string]string{
+	"JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4":  "Jupiter",
+	"JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB":  "Jupiter",
+	"675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8": "Raydium",
+	"CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK": "Raydium",
+	"CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C": "Raydium",
+	"whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc":  "Orca",
+	"9W959DqEETiGZocYWCQPaJ6sBmUzgfxXfqGeTEdp3aQP": "Orca",
+}
+
+// knownMints gives a ticker for popular mints; others are shown shortened.
+var knownMints = map[string]string{
+	WrappedSOLMint: "SOL",
+	"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v": "USDC",
+	"Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB": "USDT",
+	"DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263": "BONK",
+	"JUPyiwrYJFskUPiHa7hkeR8VUtAeFoSYbKedZNsDvCN":  "JUP",
+	"mSoLzYCxHdYgdzU16g5QSh3i5K3z3KZK7ytfqcJm7So":  "mSOL",
+}
+
+// transaction mirrors the parts of a getTransaction (jsonParsed) result we use.
+type transaction struct {
+	Slot uint64 `json:"slot"`
+	Meta *struct {
+		Err               json.RawMessage `json:"err"`
+		Fee               uint64          `json:"fee"`
+		PreBalances       []uint64        `json:"preBalances"`
+		PostBalances      []uint64        `json:"postBalances"`
+		PreTokenBalances  []tokenBalance  `json:"preTokenBalances"`
+		PostTokenBalances []tokenBalance  `json:"postTokenBalances"`
+		InnerInstructions []struct {
+			Instructions []instruction `json:"instructions"`
+		} `json:"innerInstructions"`
+	} `json:"meta"`
+	Transaction struct {
+		Signatures []string `json:"signatures"`
+		Message    struct {
+			AccountKeys  []accountKey  `json:"accountKeys"`
+			Instructions []instruction `json:"instructions"`
+		} `json:"message"`
+	} `json:"transaction"`
+}
+
+// accountKey accepts both the jsonParsed object form and a bare string.
+type accountKey struct {
+	Pubkey string `json:"pubkey"`
+	Signer bool   `json:"signer"`
+}
+
+func (k *accountKey) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &k.Pubkey)
+	}
+	type plain accountKey
+	return json.Unmarshal(b, (*plain)(k))
+}
+
+type instruction struct {
+	ProgramID string `json:"programId"`
+	Program   string `json:"program"`
+	Parsed    *struct {
+		Type string                     `json:"type"`
+		Info map[string]json.RawMessage `json:"info"`
+	} `json:"parsed"`
+}
+
+// parsedType returns the parsed instruction type, or "" for raw instructions.
+func (ix instruction) parsedType() string {
+	if ix.Parsed == nil {
+		return ""
+	}
+	return ix.Parsed.Type
+}
+
+// infoString returns a string field of the parsed info, or "".
+func (ix instruction) infoString(key string) string {
+	if ix.Parsed == nil {
+		return ""
+	}
+	var s string
+	_ = json.Unmarshal(ix.Parsed.Info[key], &s)
+	return s
+}
+
+// infoUint returns a numeric field of the parsed info, or 0.
+func (ix instruction) infoUint(key string) uint64 {
+	if ix.Parsed == nil {
+		return 0
+	}
+	var n uint64
+	_ = json.Unmarshal(ix.Parsed.Info[key], &n)
+	return n
+}
+
+type tokenBalance struct {
+	AccountIndex  int    `json:"accountIndex"`
+	Mint          string `json:"mint"`
+	Owner         string `json:"owner"`
+	UITokenAmount struct {
+		Amount   string `json:"amount"`
+		Decimals int    `json:"decimals"`
+	} `json:"uiTokenAmount"`
+}
+
+// amount returns the raw integer amount (0 if malformed).
+func (tb tokenBalance) amount() *big.Int {
+	n, ok := new(big.Int).SetString(tb.UITokenAmount.Amount, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return n
+}
+
+// allInstructions returns outer and inner instructions in one slice.
+func (t *transaction) allInstructions() []instruction {
+	out := append([]instruction(nil), t.Transaction.Message.Instructions...)
+	if t.Meta != nil {
+		for _, in := range t.Meta.InnerInstructions {
+			out = append(out, in.Instructions...)
+		}
+	}
+	return out
+}
@@ -13,7 +13,7 @@ import (
 //   b := util.NewBackoff(1*time.Second, 30*time.Second, 2.0, 0.2)
 //   for attempt := 0; attempt < 10; attempt++ {
 //       wait := b.Next()
-//       log.Printf("retrying in %s", wait)
+//       slog.Info("retrying", "attempt", b.Attempt(), "wait", wait)
 //       time.Sleep(wait)
 //       if doSomething() {
 //           b.Reset()
@@ -89,6 +89,13 @@ func (b *Backoff) Next() time.Duration {
 	return time.Duration(backoff)
 }
 
+// Attempt returns how many durations Next has returned since the last Reset.
+func (b *Backoff) Attempt() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempt
+}
+
 // Reset clears the attempt counter, so the next backoff is min.
 func (b *Backoff) Reset() {
 	b.mu.Lock()
@@ -0,0 +1,192 @@
+// Package watchdog alerts the admin chat about dropped subscriptions
+// without waiting for someone to run /health.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/logging"
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+var logger = logging.Component("watchdog")
+
+// maxListed caps the wallets named in one alert.
+const maxListed = 10
+
+// Config tunes the watchdog. Zero values disable the matching check.
+type Config struct {
+	Interval     time.Duration // how often Manager.Stats is checked
+	DroppedAfter time.Duration // a wallet must stay dropped this long before it is reported
+	Threshold    int           // report when this many wallets are dropped on two checks in a row
+	Cooldown     time.Duration // minimum time between two messages
+}
+
+// Source is what the watchdog watches; *tracker.Manager implements it.
+type Source interface {
+	Stats() tracker.ManagerStats
+	Bus() *tracker.Bus
+}
+
+// Watchdog compares Manager.Stats with what it last told the admin and
+// publishes the difference as operator events (KindError when wallets are
+// down, KindResolved when they are back) on the tracker bus, so they take
+// the same outbox and rate-limited path as every other alert.
+//
+// Debouncing: a wallet is only reported after DroppedAfter and the
+// threshold only counts wallets already dropped on the previous check, so
+// a reconnect that resubscribes quickly never alerts; and messages are at
+// least Cooldown apart, each carrying the net change since the previous one.
+type Watchdog struct {
+	tm  Source
+	cfg Config
+
+	since    map[string]time.Time // dropped wallet -> first seen dropped
+	told     map[string]bool      // wallets the admin was told are down
+	storm    bool                 // admin was told the threshold was crossed
+	lastSent time.Time
+}
+
+// New returns a Watchdog for tm.
+func New(tm Source, cfg Config) *Watchdog {
+	return &Watchdog{
+		tm:    tm,
+		cfg:   cfg,
+		since: make(map[string]time.Time),
+		told:  make(map[string]bool),
+	}
+}
+
+// Run checks every Interval until ctx is canceled. It returns at once if
+// Interval is zero.
+func (w *Watchdog) Run(ctx context.Context) {
+	if w.cfg.Interval <= 0 {
+		return
+	}
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			w.check(now.UTC())
+		}
+	}
+}
+
+// check runs one round against the current stats.
+func (w *Watchdog) check(now time.Time) {
+	st := w.tm.Stats()
+
+	dropped := make(map[string]bool, len(st.Dropped))
+	for _, a := range st.Dropped {
+		dropped[a] = true
+		if _, ok := w.since[a]; !ok {
+			w.since[a] = now
+		}
+	}
+	for a := range w.since {
+		if !dropped[a] {
+			delete(w.since, a)
+		}
+	}
+	open := make(map[string]bool, len(st.Subscribers))
+	for _, s := range st.Subscribers {
+		if s.Open {
+			open[s.Addr] = true
+		}
+	}
+
+	var down, back []string
+	var held int // dropped on the previous check too
+	for a, t := range w.since {
+		if t.Before(now) {
+			held++
+		}
+		if w.cfg.DroppedAfter > 0 && !w.told[a] && now.Sub(t) >= w.cfg.DroppedAfter {
+			down = append(down, a)
+		}
+	}
+	for a := range w.told {
+		switch {
+		case open[a]:
+			back = append(back, a)
+		case !dropped[a]:
+			delete(w.told, a) // untracked or failed (failures alert on their own)
+		}
+	}
+	storm := w.cfg.Threshold > 0 && held >= w.cfg.Threshold
+	stormChanged := storm != w.storm
+
+	if len(down) == 0 && len(back) == 0 && !stormChanged {
+		return
+	}
+	if !w.lastSent.IsZero() && now.Sub(w.lastSent) < w.cfg.Cooldown {
+		return // the next round after the cooldown reports the net change
+	}
+	sort.Strings(down)
+	sort.Strings(back)
+
+	var bad, good []string
+	if stormChanged && storm {
+		bad = append(bad, fmt.Sprintf("%d of %d subscriptions dropped (threshold %d): %s",
+			len(st.Dropped), st.Tracked, w.cfg.Threshold, list(st.Dropped)))
+	}
+	if len(down) > 0 {
+		bad = append(bad, fmt.Sprintf("%d subscription(s) dropped for over %s: %s", len(down), w.cfg.DroppedAfter, list(down)))
+	}
+	if stormChanged && !storm {
+		good = append(good, fmt.Sprintf("dropped subscriptions back below the threshold: %d of %d dropped", len(st.Dropped), st.Tracked))
+	}
+	if len(back) > 0 {
+		good = append(good, fmt.Sprintf("%d subscription(s) back: %s", len(back), list(back)))
+	}
+
+	for _, a := range down {
+		w.told[a] = true
+	}
+	for _, a := range back {
+		delete(w.told, a)
+	}
+	w.storm = storm
+	w.lastSent = now
+
+	if len(bad) > 0 {
+		w.publish(tracker.KindError, bad, now)
+	}
+	if len(good) > 0 {
+		w.publish(tracker.KindResolved, good, now)
+	}
+}
+
+func (w *Watchdog) publish(kind tracker.EventKind, lines []string, now time.Time) {
+	msg := strings.Join(lines, "\n")
+	logger.Info("alert", "kind", kind, "message", msg)
+	w.tm.Bus().Publish(tracker.Event{Kind: kind, Time: now, Message: msg})
+}
+
+// list renders up to maxListed short addresses.
+func list(addrs []string) string {
+	parts := make([]string, 0, maxListed+1)
+	for i, a := range addrs {
+		if i == maxListed {
+			parts = append(parts, fmt.Sprintf("and %d more", len(addrs)-i))
+			break
+		}
+		parts = append(parts, short(a))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// short renders an address as ABCD...WXYZ.
+func short(a string) string {
+	if len(a) <= 12 {
+		return a
+	}
+	return a[:4] + "..." + a[len(a)-4:]
+}
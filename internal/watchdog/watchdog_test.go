@@ -0,0 +1,145 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0xsamyy/solwatch/internal/tracker"
+)
+
+const (
+	walletA = "AAAAaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaAAAA"
+	walletB = "BBBBbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbBBBB"
+	walletC = "CCCCccccccccccccccccccccccccccccccccccccCCCC"
+)
+
+// fakeSource reports the dropped wallets it is given; every other wallet
+// of all is open.
+type fakeSource struct {
+	bus     *tracker.Bus
+	all     []string
+	dropped []string
+}
+
+func (f *fakeSource) Bus() *tracker.Bus { return f.bus }
+
+func (f *fakeSource) Stats() tracker.ManagerStats {
+	down := make(map[string]bool)
+	for _, a := range f.dropped {
+		down[a] = true
+	}
+	st := tracker.ManagerStats{Tracked: len(f.all), Dropped: f.dropped}
+	for _, a := range f.all {
+		st.Subscribers = append(st.Subscribers, tracker.SubscriberStats{Addr: a, Open: !down[a]})
+		if !down[a] {
+			st.Open++
+		}
+	}
+	return st
+}
+
+type harness struct {
+	t   *testing.T
+	src *fakeSource
+	wd  *Watchdog
+	sub *tracker.BusSubscription
+	now time.Time
+}
+
+func newHarness(t *testing.T, cfg Config) *harness {
+	src := &fakeSource{bus: tracker.NewBus(), all: []string{walletA, walletB, walletC}}
+	return &harness{
+		t:   t,
+		src: src,
+		wd:  New(src, cfg),
+		sub: src.bus.Subscribe("test", 10, tracker.DropNewest),
+		now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// step advances the clock by d, sets the dropped wallets and runs a check;
+// it returns the published alerts as "kind: message".
+func (h *harness) step(d time.Duration, dropped ...string) []string {
+	h.now = h.now.Add(d)
+	h.src.dropped = dropped
+	h.wd.check(h.now)
+	var out []string
+	for {
+		select {
+		case e := <-h.sub.C():
+			out = append(out, string(e.Kind)+": "+e.Message)
+		default:
+			return out
+		}
+	}
+}
+
+func (h *harness) expect(got []string, want ...string) {
+	h.t.Helper()
+	if len(got) != len(want) {
+		h.t.Fatalf("alerts %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			h.t.Fatalf("alert %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDroppedAfter(t *testing.T) {
+	h := newHarness(t, Config{DroppedAfter: time.Minute})
+	h.expect(h.step(0, walletA))
+	h.expect(h.step(30*time.Second, walletA))
+	h.expect(h.step(30*time.Second, walletA),
+		"error: 1 subscription(s) dropped for over 1m0s: AAAA...AAAA")
+	h.expect(h.step(30*time.Second, walletA)) // told once
+	h.expect(h.step(30*time.Second),
+		"resolved: 1 subscription(s) back: AAAA...AAAA")
+}
+
+func TestQuickReconnectNeverAlerts(t *testing.T) {
+	h := newHarness(t, Config{DroppedAfter: time.Minute, Threshold: 1})
+	h.expect(h.step(0, walletA, walletB))
+	h.expect(h.step(30 * time.Second))
+	h.expect(h.step(time.Minute))
+}
+
+func TestThreshold(t *testing.T) {
+	h := newHarness(t, Config{Threshold: 2})
+	// Only wallets dropped on the previous check count.
+	h.expect(h.step(0, walletA, walletB))
+	h.expect(h.step(15*time.Second, walletA, walletB),
+		"error: 2 of 3 subscriptions dropped (threshold 2): AAAA...AAAA, BBBB...BBBB")
+	h.expect(h.step(15*time.Second, walletA, walletB, walletC)) // still over: no repeat
+	h.expect(h.step(15*time.Second, walletC),
+		"resolved: dropped subscriptions back below the threshold: 1 of 3 dropped")
+}
+
+func TestCooldownReportsNetChange(t *testing.T) {
+	h := newHarness(t, Config{DroppedAfter: time.Minute, Cooldown: 5 * time.Minute})
+	h.expect(h.step(0, walletA))
+	h.expect(h.step(time.Minute, walletA),
+		"error: 1 subscription(s) dropped for over 1m0s: AAAA...AAAA")
+	// B drops and A comes back within the cooldown: nothing yet.
+	h.expect(h.step(0, walletB))
+	h.expect(h.step(time.Minute, walletB))
+	h.expect(h.step(time.Minute, walletB))
+	// After the cooldown, one round carries both changes.
+	h.expect(h.step(3*time.Minute, walletB),
+		"error: 1 subscription(s) dropped for over 1m0s: BBBB...BBBB",
+		"resolved: 1 subscription(s) back: AAAA...AAAA")
+}
+
+func TestList(t *testing.T) {
+	addrs := make([]string, maxListed+2)
+	for i := range addrs {
+		addrs[i] = walletA
+	}
+	got := list(addrs)
+	if want := "AAAA...AAAA, "; len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("list = %q", got)
+	}
+	if suffix := "and 2 more"; got[len(got)-len(suffix):] != suffix {
+		t.Errorf("list = %q, want it to end with %q", got, suffix)
+	}
+}